@@ -0,0 +1,116 @@
+package flags
+
+import (
+	"context"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// allowedSource describes where the allowed values of a flag come from
+type allowedSource struct {
+	allowed       []string
+	descriptions  map[string]string
+	aliases       map[string][]string
+	allowedFunc   AllowedFunc
+	describedFunc AllowedWithDescriptionFunc
+}
+
+// allowedSet contains the allowed values of a flag with their descriptions and aliases
+type allowedSet struct {
+	values       []string
+	descriptions map[string]string
+	aliases      map[string][]string
+}
+
+// hasFunc tells if the allowed values come from a function
+func (source allowedSource) hasFunc() bool {
+	return source.allowedFunc != nil || source.describedFunc != nil
+}
+
+// get returns the allowed values with their descriptions and aliases
+//
+// If a function is set, it is called with the command and its context.
+func (source allowedSource) get(cmd *cobra.Command, args []string, toComplete string) (allowedSet, error) {
+	if source.describedFunc != nil {
+		values, err := source.describedFunc(cmd.Context(), cmd, args, toComplete)
+		if err != nil {
+			return allowedSet{}, err
+		}
+		set := allowedSet{
+			values:       make([]string, 0, len(values)),
+			descriptions: make(map[string]string, len(values)),
+			aliases:      make(map[string][]string, len(source.aliases)),
+		}
+		for value, aliases := range source.aliases {
+			set.aliases[value] = aliases
+		}
+		for _, value := range values {
+			set.values = append(set.values, value.Value)
+			if len(value.Description) > 0 {
+				set.descriptions[value.Value] = value.Description
+			}
+			if len(value.Aliases) > 0 {
+				set.aliases[value.Value] = append(set.aliases[value.Value], value.Aliases...)
+			}
+		}
+		return set, nil
+	}
+	if source.allowedFunc != nil {
+		values, err := source.allowedFunc(cmd.Context(), cmd, args, toComplete)
+		if err != nil {
+			return allowedSet{}, err
+		}
+		return allowedSet{values: values, descriptions: source.descriptions, aliases: source.aliases}, nil
+	}
+	return source.static(), nil
+}
+
+// detachedCommand returns a command to get the allowed values from a function when no command is available,
+// like when unmarshalling a flag
+func detachedCommand() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.SetContext(context.Background())
+	return cmd
+}
+
+// static returns the allowed values that do not come from a function
+func (source allowedSource) static() allowedSet {
+	return allowedSet{values: source.allowed, descriptions: source.descriptions, aliases: source.aliases}
+}
+
+// candidates returns the given values to offer when completing a flag, followed by their aliases if requested
+func (set allowedSet) candidates(values []string, withAliases bool) []string {
+	if !withAliases || len(set.aliases) == 0 {
+		return values
+	}
+	result := append([]string{}, values...)
+	for _, value := range values {
+		result = append(result, set.aliases[value]...)
+	}
+	return result
+}
+
+// parseAllowed parses the allowed values given to the constructors
+//
+// Default values are prepended with a +, descriptions are separated from the value by a tab.
+func parseAllowed(value string) (allowed string, description string, isDefault bool) {
+	allowed, description, _ = strings.Cut(value, "\t")
+	if strings.HasPrefix(allowed, "+") {
+		return strings.TrimPrefix(allowed, "+"), description, true
+	}
+	return allowed, description, false
+}
+
+// completions returns the completions for the given values with their description, if any
+func completions(values []string, descriptions map[string]string) []cobra.Completion {
+	result := make([]cobra.Completion, 0, len(values))
+	for _, value := range values {
+		if description, found := descriptions[value]; found {
+			result = append(result, cobra.CompletionWithDesc(value, description))
+		} else {
+			result = append(result, value)
+		}
+	}
+	return result
+}
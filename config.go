@@ -0,0 +1,230 @@
+package flags
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/gildas/go-errors"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"gopkg.in/yaml.v3"
+)
+
+// ConfigError is returned when a configuration file cannot be loaded or has a value that is not allowed
+//
+// The Key is the path of the key in the file, like "issue.list.state", it is empty if the whole file is invalid.
+type ConfigError struct {
+	Filename string // The name of the configuration file
+	Key      string // The path of the key in the configuration file, if known
+	Line     int    // The line of the key in the configuration file, if known
+	Cause    error  // The error that occurred
+}
+
+// Error returns the error message
+//
+// implements error
+func (err ConfigError) Error() string {
+	var message strings.Builder
+
+	message.WriteString(err.Filename)
+	if err.Line > 0 {
+		message.WriteString(fmt.Sprintf(":%d", err.Line))
+	}
+	if len(err.Key) > 0 {
+		message.WriteString(fmt.Sprintf(": key %s", err.Key))
+	}
+	message.WriteString(fmt.Sprintf(": %s", err.Cause))
+	return message.String()
+}
+
+// Unwrap returns the error that occurred
+//
+// implements errors.Unwrap
+func (err ConfigError) Unwrap() error {
+	return err.Cause
+}
+
+// configValue is a flag value read from a configuration file
+type configValue struct {
+	key    string
+	value  string
+	values []string
+	line   int
+}
+
+// text returns the configuration value as the given flag value expects it in Set
+//
+// The values of a sequence are joined like the flag joins its values.
+func (value configValue) text(flag pflag.Value) string {
+	if value.values != nil {
+		return joinValues(flag, value.values)
+	}
+	return value.value
+}
+
+// configAnnotation is the annotation of the command that holds the configuration file given to EnableConfig
+const configAnnotation = "flags.config"
+
+// EnableConfig lets the flags of the given command and its subcommands get their values from the given configuration file
+//
+// The configuration file is written in YAML or JSON. Its top level keys are the flag names,
+// and the flags of the subcommands are in sections keyed by the subcommand names, following the command path:
+//
+//	state: open
+//	outputs: [json, table]
+//	issue:
+//	  list:
+//	    state: closed
+//
+// For the command "myapp issue list", the values of the "issue.list" section win over the values of the "issue" section,
+// which win over the top level values. The keys that are not flags of the command are ignored.
+//
+// The configuration file is read when the flags are validated (see EnableValidation), after the environment variables
+// and before the constraints and the rules are checked. Only the EnumFlag and EnumSliceFlag flags that still have their
+// default value are set, so the command line and the environment variables take precedence.
+// Their values are validated like on the command line. A configuration file that does not exist has no values.
+//
+// If a value is not allowed, the validation fails with a ConfigError that wraps an InvalidEnumValueError.
+//
+// Example:
+//
+//	cmd := flags.EnableValidation(flags.EnableConfig(&cobra.Command{Use: "myapp", RunE: run}, "/home/me/.myapp.yaml"))
+func EnableConfig(cmd *cobra.Command, filename string) *cobra.Command {
+	if cmd.Annotations == nil {
+		cmd.Annotations = map[string]string{}
+	}
+	cmd.Annotations[configAnnotation] = filename
+	return cmd
+}
+
+// LoadConfig sets the flags of the given command from the given configuration file
+//
+// The configuration file is read like with EnableConfig, but right away: the command line must be parsed,
+// this is typically called in a PersistentPreRunE. The constraints of the flags, like the MinCount and MaxCount
+// of an EnumSliceFlag, are checked again once the file is loaded.
+//
+// As the flags are validated before the PersistentPreRunE, the constraints and the rules (see AddRules)
+// are first checked without the values of the configuration file. Use EnableConfig to avoid that.
+//
+// If a value is not allowed, the returned error is a ConfigError that wraps an InvalidEnumValueError.
+//
+// Example:
+//
+//	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+//		return flags.LoadConfig(cmd, filepath.Join(os.Getenv("HOME"), ".myapp.yaml"))
+//	}
+func LoadConfig(cmd *cobra.Command, filename string) error {
+	if err := readConfig(cmd, filename); err != nil {
+		return err
+	}
+	return checkConstraints(cmd)
+}
+
+// loadConfig sets the flags of the given command from the configuration file given to EnableConfig, if any
+//
+// The configuration file is looked up from the command to the root command.
+func loadConfig(cmd *cobra.Command) error {
+	for current := cmd; current != nil; current = current.Parent() {
+		if filename, ok := current.Annotations[configAnnotation]; ok {
+			if err := readConfig(cmd, filename); err != nil && !errors.Is(err, os.ErrNotExist) {
+				return err
+			}
+			return nil
+		}
+	}
+	return nil
+}
+
+// readConfig sets the flags of the given command that still have their default value from the given configuration file
+func readConfig(cmd *cobra.Command, filename string) error {
+	payload, err := os.ReadFile(filename)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	values, err := parseConfig(payload, commandPath(cmd))
+	if err != nil {
+		return &ConfigError{Filename: filename, Cause: err}
+	}
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, flag := range names {
+		if err := setFromConfig(cmd, flag, values[flag]); err != nil {
+			return &ConfigError{Filename: filename, Key: values[flag].key, Line: values[flag].line, Cause: err}
+		}
+	}
+	return nil
+}
+
+// parseConfig parses the configuration payload and returns the values for the given command path, by flag name
+func parseConfig(payload []byte, path []string) (map[string]configValue, error) {
+	var document yaml.Node
+	if err := yaml.Unmarshal(payload, &document); err != nil {
+		return nil, err
+	}
+	values := map[string]configValue{}
+	if len(document.Content) == 0 {
+		return values, nil
+	}
+	section := document.Content[0]
+	if section.Kind != yaml.MappingNode {
+		return nil, errors.Errorf("line %d: the configuration must be a mapping of keys and values", section.Line)
+	}
+	prefix := ""
+	for depth := 0; section != nil; depth++ {
+		var next *yaml.Node
+		for i := 0; i+1 < len(section.Content); i += 2 {
+			key, value := section.Content[i], section.Content[i+1]
+			if depth < len(path) && key.Value == path[depth] && value.Kind == yaml.MappingNode {
+				next = value
+				continue
+			}
+			switch value.Kind {
+			case yaml.ScalarNode:
+				values[key.Value] = configValue{key: prefix + key.Value, value: value.Value, line: key.Line}
+			case yaml.SequenceNode:
+				items := make([]string, 0, len(value.Content))
+				for _, item := range value.Content {
+					items = append(items, item.Value)
+				}
+				values[key.Value] = configValue{key: prefix + key.Value, values: items, line: key.Line}
+			}
+		}
+		if next != nil {
+			prefix += path[depth] + "."
+		}
+		section = next
+	}
+	return values, nil
+}
+
+// setFromConfig sets the given flag of the command from its configuration value, if it still has its default value
+func setFromConfig(cmd *cobra.Command, flagName string, value configValue) error {
+	flag := cmd.Flags().Lookup(flagName)
+	if flag == nil || flag.Changed {
+		return nil
+	}
+	target, ok := flag.Value.(sourced)
+	if !ok || target.Source() != SourceDefault {
+		return nil
+	}
+	err := target.setFrom(value.text(flag.Value), SourceConfig)
+	if validator, ok := flag.Value.(Validator); ok && err == nil {
+		err = validator.Validate(cmd, cmd.Flags().Args())
+	}
+	setFlagName(err, flag.Name)
+	return err
+}
+
+// commandPath returns the names of the commands from the root command (excluded) to the given command
+func commandPath(cmd *cobra.Command) []string {
+	path := []string{}
+	for ; cmd.HasParent(); cmd = cmd.Parent() {
+		path = append([]string{cmd.Name()}, path...)
+	}
+	return path
+}
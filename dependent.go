@@ -0,0 +1,107 @@
+package flags
+
+import (
+	"context"
+	"sort"
+
+	"github.com/gildas/go-core"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// DependsOn returns an AllowedFunc that gives the allowed values according to the value of another flag
+//
+// The allowed map gives the allowed values by value of the other flag.
+// If the other flag is a slice flag, the allowed values of all its values are allowed.
+// If the other flag has no value, all the values of the allowed map are allowed.
+//
+// As the allowed values come from a function, they are used when completing the flag and when validating it
+// once the command line is parsed (see EnableValidation).
+//
+// Like any flag whose allowed values come from a function, a dependent flag that keeps its default value is not validated,
+// even if the other flag was given: with "--cloud gcp", a region that defaults to "us-east-1" stays "us-east-1".
+// Give the dependent flag no default value, or check its value in the command.
+//
+// Example:
+//
+//	cloud := flags.NewEnumFlag("+aws", "gcp")
+//	region := flags.NewEnumFlagWithFunc("", flags.DependsOn("cloud", map[string][]string{
+//		"aws": {"us-east-1", "eu-west-1"},
+//		"gcp": {"us-central1", "europe-west1"},
+//	}))
+func DependsOn(flagName string, allowed map[string][]string) AllowedFunc {
+	return func(ctx context.Context, cmd *cobra.Command, args []string, toComplete string) ([]string, error) {
+		values := flagValues(cmd, flagName)
+		if len(values) == 0 {
+			values = make([]string, 0, len(allowed))
+			for value := range allowed {
+				values = append(values, value)
+			}
+			sort.Strings(values)
+		}
+		result := []string{}
+		for _, value := range values {
+			result = union(result, allowed[value])
+		}
+		return result, nil
+	}
+}
+
+// DependsOnFunc returns an AllowedFunc that gives the allowed values according to the value of another flag
+//
+// The allowedFunc is called with each value of the other flag and the allowed values it returns are all allowed.
+// If the other flag has no value, the allowedFunc is called with an empty value.
+//
+// Like with DependsOn, a dependent flag that keeps its default value is not validated, even if the other flag was given.
+//
+// Example:
+//
+//	region := flags.NewEnumFlagWithFunc("", flags.DependsOnFunc("cloud", func(ctx context.Context, cloud string) ([]string, error) {
+//		return getRegions(ctx, cloud)
+//	}))
+func DependsOnFunc(flagName string, allowedFunc func(context context.Context, value string) ([]string, error)) AllowedFunc {
+	return func(ctx context.Context, cmd *cobra.Command, args []string, toComplete string) ([]string, error) {
+		values := flagValues(cmd, flagName)
+		if len(values) == 0 {
+			values = []string{""}
+		}
+		result := []string{}
+		for _, value := range values {
+			allowed, err := allowedFunc(ctx, value)
+			if err != nil {
+				return nil, err
+			}
+			result = union(result, allowed)
+		}
+		return result, nil
+	}
+}
+
+// flagValues returns the non empty values of the flag of the given command
+func flagValues(cmd *cobra.Command, flagName string) []string {
+	flag := cmd.Flags().Lookup(flagName)
+	if flag == nil {
+		return []string{}
+	}
+	values := []string{flag.Value.String()}
+	if slice, ok := flag.Value.(pflag.SliceValue); ok {
+		values = slice.GetSlice()
+	}
+	result := make([]string, 0, len(values))
+	for _, value := range values {
+		if len(value) > 0 {
+			result = append(result, value)
+		}
+	}
+	return result
+}
+
+// union appends the others values that are not yet in the values
+func union(values []string, others []string) []string {
+	for _, other := range others {
+		if !core.Contains(values, other) {
+			values = append(values, other)
+		}
+	}
+	return values
+}
@@ -2,9 +2,10 @@ package flags
 
 import (
 	"context"
-	"strings"
+	"encoding/json"
 
 	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
 )
 
 // AllowedFunc is a function that returns the allowed values for a flag
@@ -12,13 +13,52 @@ import (
 // See https://pkg.go.dev/github.com/spf13/cobra@v1.8.1#Command.RegisterFlagCompletionFunc
 type AllowedFunc func(context context.Context, comd *cobra.Command, args []string, toComplete string) ([]string, error)
 
+// AllowedValue describes an allowed value of a flag
+//
+// The Aliases are other spellings that are accepted for the value.
+type AllowedValue struct {
+	Value       string
+	Description string
+	Aliases     []string
+}
+
+// AllowedWithDescriptionFunc is a function that returns the allowed values for a flag with their description
+//
+// The descriptions are shown by the shells that support them (zsh, fish, powershell) when completing the flag.
+type AllowedWithDescriptionFunc func(context context.Context, cmd *cobra.Command, args []string, toComplete string) ([]AllowedValue, error)
+
 // EnumFlag represents a flag that can only have a value from a list of allowed values
 //
-// If the AllowedFunc is set, the Allowed values are ignored and the function is called to get the allowed values.
+// If the AllowedFunc or the AllowedWithDescriptionFunc is set, the Allowed values are ignored and the function is called to get the allowed values.
+//
+// The Descriptions map the allowed values to their description, they are used when completing the flag.
+//
+// If CaseInsensitive is true, the value matches the allowed values regardless of case and is stored as spelled in the allowed values.
+//
+// The Aliases map the allowed values to other spellings that are accepted and stored as the allowed value.
+// The aliases are not offered when completing the flag, unless CompleteAliases is true.
+//
+// If AbbreviationsAllowed is true, the value can be abbreviated as long as only one allowed value starts with it.
+//
+// The flag records where its value came from, see Source.
+//
+// If EnvVar is set, the flag gets its value from that environment variable when it is not given on the command line
+// (see EnableEnvironment and EnableValidation).
+//
+// The flag is marshalled as its value in JSON, YAML, and text. When unmarshalled, the value is validated against the allowed values.
 type EnumFlag struct {
-	Allowed     []string
-	AllowedFunc AllowedFunc
-	Value       string
+	Allowed                    []string
+	Descriptions               map[string]string
+	Aliases                    map[string][]string
+	AllowedFunc                AllowedFunc
+	AllowedWithDescriptionFunc AllowedWithDescriptionFunc
+	CaseInsensitive            bool
+	CompleteAliases            bool
+	AbbreviationsAllowed       bool
+	Value                      string
+	EnvVar                     string
+	origin                     Source
+	resolved                   []string
 }
 
 // NewEnumFlag creates a new EnumFlag
@@ -29,24 +69,31 @@ type EnumFlag struct {
 //
 // If more than one default value is provided, the first one is used.
 //
+// A value can be followed by a tab and its description, which is shown when completing the flag.
+//
 // Example:
 //
 //	flag := flags.NewEnumFlag("one", "+two", "three")
+//	flag := flags.NewEnumFlag("one\tThe first state", "+two\tThe second state")
 func NewEnumFlag(allowed ...string) *EnumFlag {
 	var allowedValues []string
 	var defaultValue string
+	descriptions := map[string]string{}
 
 	for _, value := range allowed {
-		if strings.HasPrefix(value, "+") && defaultValue == "" {
-			defaultValue = strings.TrimPrefix(value, "+")
-			allowedValues = append(allowedValues, strings.TrimPrefix(value, "+"))
-		} else {
-			allowedValues = append(allowedValues, value)
+		value, description, isDefault := parseAllowed(value)
+		if isDefault && defaultValue == "" {
+			defaultValue = value
 		}
+		if len(description) > 0 {
+			descriptions[value] = description
+		}
+		allowedValues = append(allowedValues, value)
 	}
 	return &EnumFlag{
-		Allowed: allowedValues,
-		Value:   defaultValue,
+		Allowed:      allowedValues,
+		Descriptions: descriptions,
+		Value:        defaultValue,
 	}
 }
 
@@ -58,6 +105,14 @@ func NewEnumFlagWithFunc(defaultValue string, allowedFunc AllowedFunc) *EnumFlag
 	}
 }
 
+// NewEnumFlagWithDescriptionFunc creates a new EnumFlag with a function to get the allowed values and their description
+func NewEnumFlagWithDescriptionFunc(defaultValue string, allowedFunc AllowedWithDescriptionFunc) *EnumFlag {
+	return &EnumFlag{
+		AllowedWithDescriptionFunc: allowedFunc,
+		Value:                      defaultValue,
+	}
+}
+
 // Type returns the type of the flag
 //
 // implements pflag.Value
@@ -74,37 +129,223 @@ func (flag EnumFlag) String() string {
 
 // Set sets the flag value
 //
+// If the allowed values come from a function, the value is validated later by Validate, as the command is not yet available.
+//
 // implements pflag.Value
 func (flag *EnumFlag) Set(value string) (err error) {
-	/* We cannot call the AllowedFunc here because the command is not yet available */
-	/*
-		if flag.AllowedFunc != nil && len(flag.Allowed) == 0 {
-			log := logger.Create("Flags", &logger.NilStream{})
-			flag.Allowed, _ = flag.AllowedFunc(log.ToContext(context.Background()), nil, nil)
+	if source := flag.source(); !source.hasFunc() {
+		matcher := flag.matcher(source.static())
+		allowed, found := matcher.match(value)
+		if !found {
+			return matcher.invalid(value)
 		}
-		for _, allowed := range flag.Allowed {
-			if value == allowed {
-				flag.Value = value
-				return nil
-			}
-		}
-		return errors.ArgumentInvalid.With("value", value, strings.Join(flag.Allowed, ", "))
-	*/
+		value = allowed
+	}
+	flag.Value = value
+	flag.origin = SourceCommandLine
+	return nil
+}
+
+// Validate validates the flag value against the allowed values
+//
+// If the allowed values come from a function, it is called with the command and its context.
+//
+// implements Validator
+func (flag *EnumFlag) Validate(cmd *cobra.Command, args []string) error {
+	allowed, err := flag.source().get(cmd, args, "")
+	if err != nil {
+		return err
+	}
+	flag.resolved = allowed.values
+	matcher := flag.matcher(allowed)
+	value, found := matcher.match(flag.Value)
+	if !found {
+		return matcher.invalid(flag.Value)
+	}
 	flag.Value = value
 	return nil
 }
 
+// Source tells where the flag value came from
+func (flag EnumFlag) Source() Source {
+	return flag.origin
+}
+
+// FromEnvironment tells if the flag value came from its environment variable
+func (flag EnumFlag) FromEnvironment() bool {
+	return flag.origin == SourceEnvironment
+}
+
+// reset resets the flag to the given default value
+func (flag *EnumFlag) reset(defaultValue string) {
+	flag.Value = defaultValue
+	flag.origin = SourceDefault
+}
+
+// setSource sets where the flag value came from
+func (flag *EnumFlag) setSource(source Source) {
+	flag.origin = source
+}
+
+// environmentVariable returns the name of the environment variable of the flag
+func (flag EnumFlag) environmentVariable() string {
+	return flag.EnvVar
+}
+
+// setEnvironmentVariable sets the name of the environment variable of the flag
+func (flag *EnumFlag) setEnvironmentVariable(name string) {
+	flag.EnvVar = name
+}
+
+// setFrom sets the flag value from the given source
+func (flag *EnumFlag) setFrom(value string, source Source) error {
+	if err := flag.Set(value); err != nil {
+		return err
+	}
+	flag.origin = source
+	return nil
+}
+
+// MarshalText marshals the flag value as text
+//
+// implements encoding.TextMarshaler
+func (flag EnumFlag) MarshalText() ([]byte, error) {
+	return []byte(flag.Value), nil
+}
+
+// UnmarshalText unmarshals the flag value from text
+//
+// The value is validated against the allowed values. If they come from a function, it is called with an empty command
+// that has no flags and a background context: DependsOn and DependsOnFunc see the other flag as having no value,
+// so a dependent value is not checked against the value of the other flag.
+// An empty value means the flag has no value.
+//
+// implements encoding.TextUnmarshaler
+func (flag *EnumFlag) UnmarshalText(payload []byte) error {
+	if len(payload) == 0 {
+		flag.Value = ""
+		flag.origin = SourceProgram
+		return nil
+	}
+	if err := flag.Set(string(payload)); err != nil {
+		return err
+	}
+	if flag.source().hasFunc() {
+		if err := flag.Validate(detachedCommand(), []string{}); err != nil {
+			return err
+		}
+	}
+	flag.origin = SourceProgram
+	return nil
+}
+
+// MarshalJSON marshals the flag value as a JSON string
+//
+// implements json.Marshaler
+func (flag EnumFlag) MarshalJSON() ([]byte, error) {
+	return json.Marshal(flag.Value)
+}
+
+// UnmarshalJSON unmarshals the flag value from a JSON string
+//
+// The value is validated against the allowed values, see UnmarshalText.
+//
+// implements json.Unmarshaler
+func (flag *EnumFlag) UnmarshalJSON(payload []byte) error {
+	var value string
+	if err := json.Unmarshal(payload, &value); err != nil {
+		return err
+	}
+	return flag.UnmarshalText([]byte(value))
+}
+
+// MarshalYAML marshals the flag value as a YAML string
+//
+// implements yaml.Marshaler
+func (flag EnumFlag) MarshalYAML() (interface{}, error) {
+	return flag.Value, nil
+}
+
+// UnmarshalYAML unmarshals the flag value from a YAML string
+//
+// The value is validated against the allowed values, see UnmarshalText.
+//
+// implements yaml.Unmarshaler
+func (flag *EnumFlag) UnmarshalYAML(node *yaml.Node) error {
+	var value string
+	if err := node.Decode(&value); err != nil {
+		return err
+	}
+	return flag.UnmarshalText([]byte(value))
+}
+
+// AtLeast tells if the flag value comes at or after the given value in the allowed values
+//
+// The allowed values are ordered as they are given, if they come from a function, the flag must have been validated.
+//
+// Example:
+//
+//	level := flags.NewEnumFlag("debug", "+info", "warn", "error")
+//	. . .
+//	if level.AtLeast("warn") { . . . }
+func (flag EnumFlag) AtLeast(value string) bool {
+	index, other := flag.indexes(value)
+	return index >= 0 && other >= 0 && index >= other
+}
+
+// AtMost tells if the flag value comes at or before the given value in the allowed values
+//
+// The allowed values are ordered as they are given, if they come from a function, the flag must have been validated.
+func (flag EnumFlag) AtMost(value string) bool {
+	index, other := flag.indexes(value)
+	return index >= 0 && other >= 0 && index <= other
+}
+
+// indexes returns the positions of the flag value and of the given value in the allowed values
+func (flag EnumFlag) indexes(value string) (index int, other int) {
+	allowed := flag.Allowed
+	if flag.source().hasFunc() {
+		allowed = flag.resolved
+	}
+	matcher := flag.matcher(allowedSet{values: allowed, aliases: flag.Aliases})
+	return matcher.index(flag.Value), matcher.index(value)
+}
+
 // CompletionFunc returns the completion function of the flag
 func (flag *EnumFlag) CompletionFunc(flagName string) (string, func(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective)) {
 	return flagName, func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
-		if flag.AllowedFunc != nil {
-			var err error
-
-			flag.Allowed, err = flag.AllowedFunc(cmd.Context(), cmd, args, toComplete)
-			if err != nil {
-				return []string{}, cobra.ShellCompDirectiveError
-			}
+		allowed, err := flag.source().get(cmd, args, toComplete)
+		if err != nil {
+			return []string{}, cobra.ShellCompDirectiveError
 		}
-		return flag.Allowed, cobra.ShellCompDirectiveDefault
+		candidates := allowed.candidates(allowed.values, flag.CompleteAliases)
+		return completions(flag.matcher(allowed).filter(candidates, toComplete), allowed.descriptions), cobra.ShellCompDirectiveDefault
+	}
+}
+
+// listAllowed returns the allowed values of the flag for the given command
+func (flag EnumFlag) listAllowed(cmd *cobra.Command) ([]string, error) {
+	allowed, err := flag.source().get(cmd, []string{}, "")
+	return allowed.values, err
+}
+
+// source returns where the allowed values of the flag come from
+func (flag EnumFlag) source() allowedSource {
+	return allowedSource{
+		allowed:       flag.Allowed,
+		descriptions:  flag.Descriptions,
+		aliases:       flag.Aliases,
+		allowedFunc:   flag.AllowedFunc,
+		describedFunc: flag.AllowedWithDescriptionFunc,
+	}
+}
+
+// matcher returns the matcher for the given allowed values
+func (flag EnumFlag) matcher(allowed allowedSet) matcher {
+	return matcher{
+		allowed:         allowed.values,
+		aliases:         allowed.aliases,
+		caseInsensitive: flag.CaseInsensitive,
+		abbreviations:   flag.AbbreviationsAllowed,
 	}
 }
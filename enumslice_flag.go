@@ -1,23 +1,95 @@
 package flags
 
 import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"sort"
 	"strings"
+	"unicode/utf8"
 
 	"github.com/gildas/go-core"
 	"github.com/gildas/go-errors"
 	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
 )
 
 // EnumSliceFlag represents a flag that can only have values from a list of allowed values
 //
 // The flag can be repeated to have multiple values.
+//
+// A value prepended with - or ! is removed from the values. If it comes first, it is removed from the default values
+// (or from all the allowed values after "all").
+//
+// If the AllowedFunc or the AllowedWithDescriptionFunc is set, the Allowed values are ignored and the function is called to get the allowed values.
+//
+// The Descriptions map the allowed values to their description, they are used when completing the flag.
+//
+// If CaseInsensitive is true, the values match the allowed values regardless of case and are stored as spelled in the allowed values.
+//
+// The Aliases map the allowed values to other spellings that are accepted and stored as the allowed value.
+// The aliases are not offered when completing the flag, unless CompleteAliases is true.
+//
+// If AbbreviationsAllowed is true, the values can be abbreviated as long as only one allowed value (or "all") starts with them.
+//
+// The Groups map names (like "@backend") to the allowed values they stand for. A group name is expanded into its values,
+// it can be removed like a value, and it is reported by GetSlice like "all".
+//
+// If PatternsAllowed is true, the values can be shell patterns (like "svc.billing.*" or "*-staging", see path.Match)
+// that are expanded into the allowed values they match. A pattern that matches no allowed value is an error.
+// When completing a pattern that does not start with a wildcard, the allowed values it matches are offered.
+//
+// If Ordered is true, the allowed values are ordered as they are given and the values can be ranges (like "info..error")
+// or comparisons (like ">=warn", ">warn", "<=warn", "<warn") that are expanded into the allowed values they cover.
+//
+// The MinCount and MaxCount, when not 0, limit the number of values the flag can have.
+// They are checked once the command line is parsed (see EnableValidation), even if the flag is not given.
+// Once the flag has MaxCount values, no more values are offered when completing the flag.
+//
+// The Exclusive sets list the values that cannot be combined together (like {"json", "yaml", "table"}).
+// A set with a single value (like {"none"}) means that value cannot be combined with any other value.
+// The conflicting values are rejected (unless "all" is given) and are not offered when completing the flag.
+//
+// The flag records where its values came from, see Source.
+//
+// If EnvVar is set, the flag gets its values from that environment variable when it is not given on the command line
+// (see EnableEnvironment and EnableValidation). The values are comma separated, like on the command line.
+//
+// The flag is marshalled as an array of its values in JSON and YAML, and as its comma separated values in text.
+// When "all" is given, it is marshalled as "all", unless MarshalAllExpanded is true, then it is marshalled as the allowed values.
+// When unmarshalled, the values are validated against the allowed values.
+//
+// The Separator separates the values given together (like "one,two"), it is a comma by default.
+// The values that contain the separator or double quotes are quoted like in CSV files (like "one,two" with the double quotes).
+// String always renders the values like pflag's StringSlice, comma separated in square brackets, so cmd.Flags().GetStringSlice works
+// whatever the Separator, and Set(String()) gives the same values.
+// The Separator cannot be a double quote, a carriage return, or a line feed, Set and MarshalText fail with such a separator.
 type EnumSliceFlag struct {
-	Allowed     []string
-	Values      []string
-	Default     []string
-	AllowedFunc AllowedFunc
-	AllAllowed  bool
-	all         bool
+	Allowed                    []string
+	Descriptions               map[string]string
+	Aliases                    map[string][]string
+	Groups                     map[string][]string
+	Values                     []string
+	Default                    []string
+	AllowedFunc                AllowedFunc
+	AllowedWithDescriptionFunc AllowedWithDescriptionFunc
+	AllAllowed                 bool
+	CaseInsensitive            bool
+	CompleteAliases            bool
+	AbbreviationsAllowed       bool
+	PatternsAllowed            bool
+	Ordered                    bool
+	MinCount                   int
+	MaxCount                   int
+	Exclusive                  [][]string
+	MarshalAllExpanded         bool
+	Separator                  rune
+	EnvVar                     string
+	origin                     Source
+	resolved                   []string
+	all                        bool
+	groups                     []string
+	changed                    bool
 }
 
 // Type returns the type of the flag
@@ -33,24 +105,31 @@ func (flag EnumSliceFlag) Type() string {
 //
 // If no default value is provided, the flag will not have a default value.
 //
+// A value can be followed by a tab and its description, which is shown when completing the flag.
+//
 // Example:
 //
 //	flag := flags.NewEnumSliceFlag("+one", "+two", "three")
+//	flag := flags.NewEnumSliceFlag("+one\tThe first state", "+two\tThe second state", "three")
 func NewEnumSliceFlag(allowed ...string) *EnumSliceFlag {
 	var allowedValues []string
 	var defaultValues []string
+	descriptions := map[string]string{}
 
 	for _, value := range allowed {
-		if strings.HasPrefix(value, "+") {
-			defaultValues = append(defaultValues, strings.TrimPrefix(value, "+"))
-			allowedValues = append(allowedValues, strings.TrimPrefix(value, "+"))
-		} else {
-			allowedValues = append(allowedValues, value)
+		value, description, isDefault := parseAllowed(value)
+		if isDefault {
+			defaultValues = append(defaultValues, value)
+		}
+		if len(description) > 0 {
+			descriptions[value] = description
 		}
+		allowedValues = append(allowedValues, value)
 	}
 	return &EnumSliceFlag{
-		Allowed: allowedValues,
-		Default: defaultValues,
+		Allowed:      allowedValues,
+		Descriptions: descriptions,
+		Default:      defaultValues,
 	}
 }
 
@@ -62,6 +141,14 @@ func NewEnumSliceFlagWithFunc(allowedFunc AllowedFunc, defaultvalues ...string)
 	}
 }
 
+// NewEnumSliceFlagWithDescriptionFunc creates a new EnumSliceFlag with a function to get the allowed values and their description
+func NewEnumSliceFlagWithDescriptionFunc(allowedFunc AllowedWithDescriptionFunc, defaultvalues ...string) *EnumSliceFlag {
+	return &EnumSliceFlag{
+		AllowedWithDescriptionFunc: allowedFunc,
+		Default:                    append([]string{}, defaultvalues...),
+	}
+}
+
 // NewEnumSliceFlagWithAllAllowed creates a new EnumSliceFlag
 //
 // The default values are prepended with a +.
@@ -86,62 +173,355 @@ func NewEnumSliceFlagWithAllAllowedAndFunc(allowedFunc AllowedFunc, defaultvalue
 	return flag
 }
 
+// NewEnumSliceFlagWithAllAllowedAndDescriptionFunc creates a new EnumSliceFlag
+func NewEnumSliceFlagWithAllAllowedAndDescriptionFunc(allowedFunc AllowedWithDescriptionFunc, defaultvalues ...string) *EnumSliceFlag {
+	flag := NewEnumSliceFlagWithDescriptionFunc(allowedFunc, defaultvalues...)
+	flag.AllAllowed = true
+	return flag
+}
+
 // String returns the string representation of the flag
 //
+// Like pflag's StringSlice, the values are separated by commas and quoted if needed, in square brackets, whatever the Separator.
+//
+// If the flag was not set, the default values are rendered.
+//
 // implements fmt.Stringer and pflag.Value
 func (flag EnumSliceFlag) String() string {
-	var result strings.Builder
+	return "[" + writeCSV(flag.current(), ',') + "]"
+}
 
-	result.WriteString("[")
-	for i, value := range flag.Values {
-		if i > 0 {
-			result.WriteString(",")
+// Set sets the flag value
+//
+// The value can contain several values separated by the Separator, quoted if needed.
+//
+// A value in square brackets is read like String renders it, comma separated, so Set(String()) gives the same values.
+// Thus "[]" gives no values, and a value that starts with "[" and ends with "]" must be quoted, like "\"[x]\"".
+//
+// If the allowed values come from a function, the values are kept as they are and validated later by Validate, as the command is not yet available.
+//
+// implements pflag.Value
+func (flag *EnumSliceFlag) Set(value string) error {
+	values, err := flag.parse(value)
+	if err != nil {
+		return err
+	}
+	if err := flag.setValues(values); err != nil {
+		return err
+	}
+	flag.origin = SourceCommandLine
+	return nil
+}
+
+// setValues adds the given values to the flag values
+//
+// If the allowed values come from a function, the values are kept as they are, otherwise they are added if they are allowed.
+func (flag *EnumSliceFlag) setValues(values []string) error {
+	if source := flag.source(); !source.hasFunc() && len(values) > 0 {
+		return flag.add(values, source.static())
+	}
+	flag.changed = true
+	flag.appendValues(values)
+	return nil
+}
+
+// parse parses the given value into values, it can be in square brackets like String renders it
+func (flag EnumSliceFlag) parse(value string) ([]string, error) {
+	if len(value) > 1 && strings.HasPrefix(value, "[") && strings.HasSuffix(value, "]") {
+		if len(value) == 2 {
+			return []string{}, nil
 		}
-		result.WriteString(value)
+		return readCSV(value[1:len(value)-1], ',')
 	}
-	result.WriteString("]")
-	return result.String()
+	return flag.split(value)
 }
 
-// Set sets the flag value
+// split splits the given value into the values separated by the Separator
+func (flag EnumSliceFlag) split(value string) ([]string, error) {
+	if err := flag.checkSeparator(); err != nil {
+		return nil, err
+	}
+	return readCSV(value, flag.separator())
+}
+
+// readCSV reads the values of the given CSV value, separated by the given separator
+func readCSV(value string, separator rune) ([]string, error) {
+	if len(value) == 0 {
+		return []string{""}, nil
+	}
+	reader := csv.NewReader(strings.NewReader(value))
+	reader.Comma = separator
+	reader.FieldsPerRecord = -1
+	records, err := reader.ReadAll()
+	if err != nil {
+		invalid := errors.ArgumentInvalid
+		invalid.What = "value"
+		invalid.Value = value
+		return nil, invalid.Wrap(err)
+	}
+	values := []string{}
+	for _, record := range records {
+		values = append(values, record...)
+	}
+	return values, nil
+}
+
+// join joins the given values with the Separator, quoting them if needed
+func (flag EnumSliceFlag) join(values []string) string {
+	return writeCSV(values, flag.separator())
+}
+
+// writeCSV joins the given values with the given separator, quoting them if needed
 //
-// implements pflag.Value
-func (flag *EnumSliceFlag) Set(value string) (err error) {
-	if flag.AllowedFunc != nil && len(flag.Allowed) == 0 { // Unfortunatly, as of now, we cannot call the function to get the allowed values
-		// TODO: Find a way to call the function to get the allowed values
-		return flag.Append(value) // so we just add the value
+// If the separator cannot be used to quote the values, they are joined as they are.
+func writeCSV(values []string, separator rune) string {
+	var buffer bytes.Buffer
+
+	writer := csv.NewWriter(&buffer)
+	writer.Comma = separator
+	if err := writer.Write(values); err != nil {
+		return strings.Join(values, string(separator))
 	}
-	if value == "all" && flag.AllAllowed {
-		flag.Values = flag.Allowed
-		flag.all = true
+	writer.Flush()
+	return strings.TrimSuffix(buffer.String(), "\n")
+}
+
+// separator returns the separator of the values
+func (flag EnumSliceFlag) separator() rune {
+	if flag.Separator == 0 {
+		return ','
+	}
+	return flag.Separator
+}
+
+// checkSeparator checks the Separator can separate quoted values
+//
+// Like in CSV files, the separator cannot be a double quote, a carriage return, a line feed, or an invalid rune.
+func (flag EnumSliceFlag) checkSeparator() error {
+	separator := flag.separator()
+	if separator == '"' || separator == '\r' || separator == '\n' || separator == utf8.RuneError || !utf8.ValidRune(separator) {
+		return errors.ArgumentInvalid.With("separator", string(separator))
+	}
+	return nil
+}
+
+// Validate validates the flag values against the allowed values
+//
+// If the allowed values come from a function, it is called with the command and its context,
+// then the values kept by Set are checked and "all" is expanded.
+//
+// implements Validator
+func (flag *EnumSliceFlag) Validate(cmd *cobra.Command, args []string) error {
+	if !flag.source().hasFunc() {
+		return nil
+	}
+	allowed, err := flag.source().get(cmd, args, "")
+	if err != nil {
+		return err
+	}
+	flag.resolved = allowed.values
+	values := flag.Values
+	flag.Values = make([]string, 0, len(values))
+	flag.all = false
+	flag.groups = nil
+	flag.changed = false
+	if len(values) == 0 {
 		return nil
 	}
-	found := false
-	for _, v := range strings.Split(value, ",") {
-		for _, allowed := range flag.Allowed {
-			if v == allowed {
-				found = true
-				if !core.Contains(flag.Values, v) {
-					flag.Values = append(flag.Values, v)
+	return flag.add(values, allowed)
+}
+
+// add adds the given values to the flag values if they are allowed
+//
+// The values prepended with - or ! are removed from the flag values, which start from the default values if nothing was added yet.
+//
+// If PatternsAllowed is true, the patterns are expanded into the allowed values they match.
+//
+// If Ordered is true, the ranges and comparisons are expanded into the allowed values they cover.
+//
+// The values that are not allowed are all reported in the returned error.
+func (flag *EnumSliceFlag) add(values []string, allowed allowedSet) error {
+	matcher := flag.matcher(allowed)
+	invalid := []string{}
+	for _, v := range values {
+		if values, found := resolve(v, matcher); found {
+			for _, value := range values {
+				flag.insert(value, allowed)
+			}
+			continue
+		}
+		if excluded, isExclusion := exclusion(v); isExclusion {
+			if values, found := resolve(excluded, matcher); found {
+				for _, value := range values {
+					flag.remove(value, allowed)
 				}
+				continue
 			}
+			v = excluded
 		}
+		invalid = append(invalid, v)
 	}
-	if found {
+	if len(invalid) > 0 {
+		return matcher.invalid(invalid...)
+	}
+	if conflict := flag.conflict(); len(conflict) > 0 {
+		return &ConflictingValuesError{Values: conflict}
+	}
+	return nil
+}
+
+// conflict returns the first two flag values that cannot be combined, if any
+func (flag EnumSliceFlag) conflict() []string {
+	if flag.all {
 		return nil
 	}
-	return errors.ArgumentInvalid.With("value", value, strings.Join(flag.Allowed, ", "))
+	for i, value := range flag.Values {
+		for _, other := range flag.Values[i+1:] {
+			if flag.excludes(value, other) {
+				return []string{value, other}
+			}
+		}
+	}
+	return nil
 }
 
-// Append appends a value to the flag
+// excludes tells if the given values cannot be combined according to the Exclusive sets
+func (flag EnumSliceFlag) excludes(value, other string) bool {
+	if value == other {
+		return false
+	}
+	for _, set := range flag.Exclusive {
+		if len(set) == 1 && (set[0] == value || set[0] == other) {
+			return true
+		}
+		if len(set) > 1 && core.Contains(set, value) && core.Contains(set, other) {
+			return true
+		}
+	}
+	return false
+}
+
+// resolve returns the allowed values (or keywords) the given value stands for
 //
-// implements pflag.SliceValue
-func (flag *EnumSliceFlag) Append(value string) error {
-	for _, v := range strings.Split(value, ",") {
+// The value can be an allowed value, a keyword, a pattern, or a range.
+func resolve(value string, matcher matcher) ([]string, bool) {
+	if canonical, found := matcher.match(value); found {
+		return []string{canonical}, true
+	}
+	if matches, isPattern := matcher.expand(value); isPattern {
+		return matches, len(matches) > 0
+	}
+	if values, isRange := matcher.span(value); isRange {
+		return values, len(values) > 0
+	}
+	return nil, false
+}
+
+// insert inserts the given allowed value (or all of them, or the values of a group) in the flag values
+func (flag *EnumSliceFlag) insert(value string, allowed allowedSet) {
+	flag.changed = true
+	if flag.AllAllowed && value == "all" && !core.Contains(allowed.values, "all") {
+		flag.Values = append([]string{}, allowed.values...)
+		flag.all = true
+		return
+	}
+	inserted := []string{value}
+	if members, isGroup := flag.members(value, allowed); isGroup {
+		inserted = members
+		if !core.Contains(flag.groups, value) {
+			flag.groups = append(flag.groups, value)
+		}
+	}
+	for _, v := range inserted {
 		if !core.Contains(flag.Values, v) {
 			flag.Values = append(flag.Values, v)
 		}
 	}
+}
+
+// remove removes the given allowed value (or all of them, or the values of a group) from the flag values
+//
+// If nothing was added yet, the value is removed from the default values.
+func (flag *EnumSliceFlag) remove(value string, allowed allowedSet) {
+	if !flag.changed {
+		flag.Values = append([]string{}, flag.Default...)
+		flag.changed = true
+	}
+	flag.all = false
+	if flag.AllAllowed && value == "all" && !core.Contains(allowed.values, "all") {
+		flag.Values = []string{}
+		flag.groups = nil
+		return
+	}
+	removed := []string{value}
+	if members, isGroup := flag.members(value, allowed); isGroup {
+		removed = members
+	}
+	values := make([]string, 0, len(flag.Values))
+	for _, v := range flag.Values {
+		if !core.Contains(removed, v) {
+			values = append(values, v)
+		}
+	}
+	flag.Values = values
+	groups := make([]string, 0, len(flag.groups))
+	for _, group := range flag.groups {
+		if members, _ := flag.members(group, allowed); flag.containsAll(members) {
+			groups = append(groups, group)
+		}
+	}
+	flag.groups = groups
+}
+
+// members returns the allowed values of the given group, if it is a group
+//
+// The members are spelled as in the allowed values, the members that are not allowed are ignored.
+func (flag EnumSliceFlag) members(group string, allowed allowedSet) ([]string, bool) {
+	members, isGroup := flag.Groups[group]
+	if !isGroup || core.Contains(allowed.values, group) {
+		return nil, false
+	}
+	matcher := flag.matcher(allowed)
+	result := make([]string, 0, len(members))
+	for _, member := range members {
+		if canonical, found := matcher.match(member); found && core.Contains(allowed.values, canonical) {
+			result = append(result, canonical)
+		}
+	}
+	return result, true
+}
+
+// containsAll tells if the flag values contain all the given values
+func (flag EnumSliceFlag) containsAll(values []string) bool {
+	for _, value := range values {
+		if !core.Contains(flag.Values, value) {
+			return false
+		}
+	}
+	return true
+}
+
+// exclusion tells if the given value is prepended with - or ! and returns the value to remove
+func exclusion(value string) (string, bool) {
+	if strings.HasPrefix(value, "-") || strings.HasPrefix(value, "!") {
+		return value[1:], true
+	}
+	return value, false
+}
+
+// Append appends a value to the flag
+//
+// The value can contain several values separated by the Separator, quoted if needed.
+//
+// implements pflag.SliceValue
+func (flag *EnumSliceFlag) Append(value string) error {
+	values, err := flag.split(value)
+	if err != nil {
+		return err
+	}
+	flag.changed = true
+	flag.origin = SourceProgram
+	flag.appendValues(values)
 	return nil
 }
 
@@ -150,26 +530,235 @@ func (flag *EnumSliceFlag) Append(value string) error {
 // implements pflag.SliceValue
 func (flag *EnumSliceFlag) Replace(values []string) error {
 	flag.Values = make([]string, 0, len(values))
+	flag.all = false
+	flag.groups = nil
+	flag.changed = true
+	flag.origin = SourceProgram
+	flag.appendValues(values)
+	return nil
+}
+
+// appendValues appends the given values to the flag values, if they are not already there
+func (flag *EnumSliceFlag) appendValues(values []string) {
 	for _, value := range values {
-		_ = flag.Append(value)
+		if !core.Contains(flag.Values, value) {
+			flag.Values = append(flag.Values, value)
+		}
 	}
-	return nil
 }
 
 // GetSlice returns the flag value list as a slice of strings
 //
 // implements pflag.SliceValue
 func (flag EnumSliceFlag) GetSlice() []string {
-	if len(flag.Values) == 0 {
-		// TODO: Find a way to call the function to get the allowed values to build the default values (if any)
-		return flag.Default
-	}
 	if flag.all {
 		return append(append([]string{}, "all"), flag.Values...)
 	}
+	if len(flag.groups) > 0 {
+		return append(append([]string{}, flag.groups...), flag.Values...)
+	}
+	return flag.current()
+}
+
+// current returns the flag values, or the default values if the flag was not set
+func (flag EnumSliceFlag) current() []string {
+	if len(flag.Values) == 0 && !flag.changed {
+		return flag.Default
+	}
 	return flag.Values
 }
 
+// checkConstraints checks the number of values of the flag against its MinCount and MaxCount
+func (flag EnumSliceFlag) checkConstraints(flagName string) error {
+	values := flag.current()
+	if (flag.MinCount > 0 && len(values) < flag.MinCount) || (flag.MaxCount > 0 && len(values) > flag.MaxCount) {
+		return &InvalidValueCountError{FlagName: flagName, Values: values, MinCount: flag.MinCount, MaxCount: flag.MaxCount}
+	}
+	return nil
+}
+
+// Source tells where the flag values came from
+func (flag EnumSliceFlag) Source() Source {
+	return flag.origin
+}
+
+// FromEnvironment tells if the flag values came from its environment variable
+func (flag EnumSliceFlag) FromEnvironment() bool {
+	return flag.origin == SourceEnvironment
+}
+
+// reset resets the flag to its Default values
+//
+// The given default value is ignored, the Default values are used instead.
+func (flag *EnumSliceFlag) reset(defaultValue string) {
+	flag.Values = nil
+	flag.all = false
+	flag.groups = nil
+	flag.changed = false
+	flag.origin = SourceDefault
+}
+
+// setSource sets where the flag value came from
+func (flag *EnumSliceFlag) setSource(source Source) {
+	flag.origin = source
+}
+
+// environmentVariable returns the name of the environment variable of the flag
+func (flag EnumSliceFlag) environmentVariable() string {
+	return flag.EnvVar
+}
+
+// setEnvironmentVariable sets the name of the environment variable of the flag
+func (flag *EnumSliceFlag) setEnvironmentVariable(name string) {
+	flag.EnvVar = name
+}
+
+// setFrom sets the flag values from the given source
+func (flag *EnumSliceFlag) setFrom(value string, source Source) error {
+	if err := flag.Set(value); err != nil {
+		return err
+	}
+	flag.origin = source
+	return nil
+}
+
+// MarshalText marshals the flag values as text, separated by the Separator and quoted if needed
+//
+// implements encoding.TextMarshaler
+func (flag EnumSliceFlag) MarshalText() ([]byte, error) {
+	if err := flag.checkSeparator(); err != nil {
+		return nil, err
+	}
+	return []byte(flag.join(flag.marshalValues())), nil
+}
+
+// UnmarshalText unmarshals the flag values from text, separated by the Separator and quoted if needed
+//
+// The text can be in square brackets, like String renders it (see Set).
+//
+// The values are validated against the allowed values. If they come from a function, it is called with an empty command
+// that has no flags and a background context: DependsOn and DependsOnFunc see the other flag as having no value,
+// so dependent values are not checked against the value of the other flag.
+//
+// implements encoding.TextUnmarshaler
+func (flag *EnumSliceFlag) UnmarshalText(payload []byte) error {
+	if len(payload) == 0 {
+		return flag.unmarshalValues([]string{})
+	}
+	values, err := flag.parse(string(payload))
+	if err != nil {
+		return err
+	}
+	return flag.unmarshalValues(values)
+}
+
+// MarshalJSON marshals the flag values as a JSON array
+//
+// implements json.Marshaler
+func (flag EnumSliceFlag) MarshalJSON() ([]byte, error) {
+	return json.Marshal(flag.marshalValues())
+}
+
+// UnmarshalJSON unmarshals the flag values from a JSON array or a JSON string with comma separated values
+//
+// The values are validated against the allowed values, see UnmarshalText.
+//
+// implements json.Unmarshaler
+func (flag *EnumSliceFlag) UnmarshalJSON(payload []byte) error {
+	var values []string
+	if err := json.Unmarshal(payload, &values); err != nil {
+		var value string
+		if json.Unmarshal(payload, &value) != nil {
+			return err
+		}
+		return flag.UnmarshalText([]byte(value))
+	}
+	return flag.unmarshalValues(values)
+}
+
+// MarshalYAML marshals the flag values as a YAML sequence
+//
+// implements yaml.Marshaler
+func (flag EnumSliceFlag) MarshalYAML() (interface{}, error) {
+	return flag.marshalValues(), nil
+}
+
+// UnmarshalYAML unmarshals the flag values from a YAML sequence or a YAML string with comma separated values
+//
+// The values are validated against the allowed values, see UnmarshalText.
+//
+// implements yaml.Unmarshaler
+func (flag *EnumSliceFlag) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		return flag.UnmarshalText([]byte(node.Value))
+	}
+	var values []string
+	if err := node.Decode(&values); err != nil {
+		return err
+	}
+	return flag.unmarshalValues(values)
+}
+
+// marshalValues returns the values to marshal
+func (flag EnumSliceFlag) marshalValues() []string {
+	if flag.all && !flag.MarshalAllExpanded {
+		return []string{"all"}
+	}
+	return append([]string{}, flag.current()...)
+}
+
+// unmarshalValues replaces the flag values with the given values, once validated
+func (flag *EnumSliceFlag) unmarshalValues(values []string) error {
+	flag.reset("")
+	if len(values) > 0 {
+		if err := flag.setValues(values); err != nil {
+			return err
+		}
+		if flag.source().hasFunc() {
+			if err := flag.Validate(detachedCommand(), []string{}); err != nil {
+				return err
+			}
+		}
+	}
+	flag.changed = true
+	flag.origin = SourceProgram
+	return nil
+}
+
+// AtLeast tells if all the flag values come at or after the given value in the allowed values
+//
+// The allowed values are ordered as they are given, if they come from a function, the flag must have been validated.
+func (flag EnumSliceFlag) AtLeast(value string) bool {
+	return flag.compare(value, func(index, other int) bool { return index >= other })
+}
+
+// AtMost tells if all the flag values come at or before the given value in the allowed values
+//
+// The allowed values are ordered as they are given, if they come from a function, the flag must have been validated.
+func (flag EnumSliceFlag) AtMost(value string) bool {
+	return flag.compare(value, func(index, other int) bool { return index <= other })
+}
+
+// compare compares the position of each flag value with the position of the given value in the allowed values
+func (flag EnumSliceFlag) compare(value string, compare func(index, other int) bool) bool {
+	allowed := flag.Allowed
+	if flag.source().hasFunc() {
+		allowed = flag.resolved
+	}
+	matcher := flag.matcher(allowedSet{values: allowed, aliases: flag.Aliases})
+	other := matcher.index(value)
+	values := flag.current()
+	if other < 0 || len(values) == 0 {
+		return false
+	}
+	for _, v := range values {
+		if index := matcher.index(v); index < 0 || !compare(index, other) {
+			return false
+		}
+	}
+	return true
+}
+
 // CompletionFunc returns the completion function of the flag
 //
 // This function is used by the cobra.Command when it needs to complete the flag value.
@@ -177,27 +766,123 @@ func (flag EnumSliceFlag) GetSlice() []string {
 // See: https://pkg.go.dev/github.com/spf13/cobra#Command.RegisterFlagCompletionFunc
 func (flag EnumSliceFlag) CompletionFunc(flagName string) (string, func(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective)) {
 	return flagName, func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
-		var allowed []string
-		var err error
-
-		if flag.AllowedFunc != nil {
-			allowed, err = flag.AllowedFunc(cmd.Context(), cmd, args, toComplete)
-			if err != nil {
-				return []string{}, cobra.ShellCompDirectiveError
-			}
-		} else {
-			allowed = make([]string, 0, len(flag.Allowed))
-			if current, err := cmd.Flags().GetStringSlice(flagName); err == nil {
-				for _, value := range flag.Allowed {
-					if !core.Contains(current, value) {
-						allowed = append(allowed, value)
-					}
+		flag := flag.registered(cmd, flagName)
+		if flag.MaxCount > 0 && len(flag.Values) >= flag.MaxCount {
+			return []string{}, cobra.ShellCompDirectiveNoFileComp
+		}
+		allowed, err := flag.source().get(cmd, args, toComplete)
+		if err != nil {
+			return []string{}, cobra.ShellCompDirectiveError
+		}
+		remaining := allowed.values
+		if !flag.source().hasFunc() || len(flag.Exclusive) > 0 {
+			remaining = make([]string, 0, len(allowed.values))
+			for _, value := range allowed.values {
+				if !flag.source().hasFunc() && core.Contains(flag.Values, value) {
+					continue
+				}
+				if !core.ContainsWithFunc(flag.Values, value, flag.excludes) {
+					remaining = append(remaining, value)
 				}
 			}
 		}
-		if flag.AllAllowed && len(allowed) > 0 {
-			allowed = append(allowed, "all")
+		matcher := flag.matcher(allowed)
+		if matcher.isPattern(toComplete) {
+			// Shells keep only the completions that start with what was typed,
+			// the matches of a pattern starting with a wildcard would never show
+			if len(literalPrefix(toComplete)) == 0 {
+				return []string{}, cobra.ShellCompDirectiveNoFileComp
+			}
+			matches, _ := matcher.expand(completionPattern(toComplete))
+			return completions(intersect(matches, remaining), allowed.descriptions), cobra.ShellCompDirectiveNoFileComp
+		}
+		candidates := allowed.candidates(remaining, flag.CompleteAliases)
+		result := completions(matcher.filter(candidates, toComplete), allowed.descriptions)
+		for _, group := range matcher.filter(flag.groupNames(), toComplete) {
+			if members, _ := flag.members(group, allowed); !flag.containsAll(members) {
+				result = append(result, cobra.CompletionWithDesc(group, strings.Join(members, ", ")))
+			}
+		}
+		if flag.AllAllowed && len(remaining) > 0 && matcher.hasPrefix("all", toComplete) {
+			result = append(result, "all")
+		}
+		return result, cobra.ShellCompDirectiveDefault
+	}
+}
+
+// enumSliceValue describes a flag value that is an EnumSliceFlag, like a TypedEnumSliceFlag that embeds one
+type enumSliceValue interface {
+	enumSlice() *EnumSliceFlag
+}
+
+// enumSlice returns the flag itself
+//
+// implements enumSliceValue
+func (flag *EnumSliceFlag) enumSlice() *EnumSliceFlag {
+	return flag
+}
+
+// registered returns the flag as registered in the command
+//
+// The values given so far on the command line are in that flag, not in the copy held by the completion function.
+func (flag EnumSliceFlag) registered(cmd *cobra.Command, flagName string) EnumSliceFlag {
+	if found := cmd.Flags().Lookup(flagName); found != nil {
+		if registered, ok := found.Value.(enumSliceValue); ok {
+			return *registered.enumSlice()
 		}
-		return allowed, cobra.ShellCompDirectiveDefault
 	}
+	return flag
+}
+
+// listAllowed returns the allowed values of the flag for the given command
+//
+// The group names and "all" (if AllAllowed is true) are part of the returned values.
+func (flag EnumSliceFlag) listAllowed(cmd *cobra.Command) ([]string, error) {
+	allowed, err := flag.source().get(cmd, []string{}, "")
+	if err != nil {
+		return nil, err
+	}
+	values := append(append([]string{}, allowed.values...), flag.groupNames()...)
+	if flag.AllAllowed {
+		values = append(values, "all")
+	}
+	return values, nil
+}
+
+// groupNames returns the names of the groups, sorted
+func (flag EnumSliceFlag) groupNames() []string {
+	names := make([]string, 0, len(flag.Groups))
+	for name := range flag.Groups {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// source returns where the allowed values of the flag come from
+func (flag EnumSliceFlag) source() allowedSource {
+	return allowedSource{
+		allowed:       flag.Allowed,
+		descriptions:  flag.Descriptions,
+		aliases:       flag.Aliases,
+		allowedFunc:   flag.AllowedFunc,
+		describedFunc: flag.AllowedWithDescriptionFunc,
+	}
+}
+
+// matcher returns the matcher for the given allowed values
+func (flag EnumSliceFlag) matcher(allowed allowedSet) matcher {
+	matcher := matcher{
+		allowed:         allowed.values,
+		aliases:         allowed.aliases,
+		caseInsensitive: flag.CaseInsensitive,
+		abbreviations:   flag.AbbreviationsAllowed,
+		patterns:        flag.PatternsAllowed,
+		ordered:         flag.Ordered,
+	}
+	matcher.keywords = flag.groupNames()
+	if flag.AllAllowed {
+		matcher.keywords = append(matcher.keywords, "all")
+	}
+	return matcher
 }
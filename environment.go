@@ -0,0 +1,85 @@
+package flags
+
+import (
+	"os"
+	"strings"
+
+	"github.com/gildas/go-errors"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// environmental describes a flag value that can fall back to an environment variable
+type environmental interface {
+	sourced
+	environmentVariable() string
+	setEnvironmentVariable(name string)
+}
+
+// EnableEnvironment lets the flags of the given command and its subcommands fall back to environment variables
+//
+// The EnumFlag and EnumSliceFlag flags without an EnvVar get one derived from the prefix and the flag name,
+// like APP_LOG_LEVEL for the prefix "APP" and the flag "log-level" (see EnvironmentVariableName).
+//
+// The environment variables are read when the flags are validated (see EnableValidation).
+//
+// Flags and subcommands added after this call are not affected.
+//
+// Example:
+//
+//	cmd := flags.EnableValidation(flags.EnableEnvironment(&cobra.Command{Use: "myapp", RunE: run}, "MYAPP"))
+func EnableEnvironment(cmd *cobra.Command, prefix string) *cobra.Command {
+	bind := func(flag *pflag.Flag) {
+		if value, ok := flag.Value.(environmental); ok && len(value.environmentVariable()) == 0 {
+			value.setEnvironmentVariable(EnvironmentVariableName(prefix, flag.Name))
+		}
+	}
+	cmd.Flags().VisitAll(bind)
+	cmd.PersistentFlags().VisitAll(bind)
+	for _, child := range cmd.Commands() {
+		EnableEnvironment(child, prefix)
+	}
+	return cmd
+}
+
+// EnvironmentVariableName returns the name of the environment variable for the given prefix and flag name
+//
+// Example:
+//
+//	flags.EnvironmentVariableName("APP", "log-level") // APP_LOG_LEVEL
+func EnvironmentVariableName(prefix, flagName string) string {
+	name := strings.ToUpper(strings.ReplaceAll(flagName, "-", "_"))
+	if len(prefix) > 0 {
+		return strings.ToUpper(prefix) + "_" + name
+	}
+	return name
+}
+
+// loadEnvironment sets the flags of the given command that were not given on the command line from their environment variable
+//
+// Empty environment variables are ignored.
+func loadEnvironment(cmd *cobra.Command) (err error) {
+	cmd.Flags().VisitAll(func(flag *pflag.Flag) {
+		if err != nil || flag.Changed {
+			return
+		}
+		if value, ok := flag.Value.(environmental); ok && len(value.environmentVariable()) > 0 {
+			name := value.environmentVariable()
+			if env := os.Getenv(name); len(env) > 0 {
+				if err = value.setFrom(env, SourceEnvironment); err != nil {
+					setFlagName(err, flag.Name)
+					err = environmentError(name, env, err)
+				}
+			}
+		}
+	})
+	return
+}
+
+// environmentError wraps the given error in an errors.EnvironmentInvalid error for the given environment variable
+func environmentError(name, value string, err error) error {
+	invalid := errors.EnvironmentInvalid
+	invalid.What = name
+	invalid.Value = value
+	return invalid.Wrap(err)
+}
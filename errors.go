@@ -0,0 +1,176 @@
+package flags
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gildas/go-core"
+	"github.com/gildas/go-errors"
+)
+
+// InvalidEnumValueError is returned when an EnumFlag or an EnumSliceFlag gets values that are not allowed
+//
+// The FlagName is set once the flag is known, i.e. when the flags are validated after the command line is parsed.
+//
+// The error is an errors.ArgumentInvalid error:
+//
+//	errors.Is(err, errors.ArgumentInvalid) // true
+//
+// Example:
+//
+//	var invalid *flags.InvalidEnumValueError
+//	if errors.As(err, &invalid) {
+//		fmt.Println(invalid.Values, invalid.Suggestions)
+//	}
+type InvalidEnumValueError struct {
+	FlagName    string              // The name of the flag, if known
+	Values      []string            // The values that are not allowed
+	Allowed     []string            // The allowed values
+	Suggestions map[string][]string // The closest allowed values, by invalid value
+	Candidates  map[string][]string // The allowed values an ambiguous abbreviation could stand for, by invalid value
+	NoMatch     []string            // The patterns, ranges, or comparisons that match no allowed value
+}
+
+// maxListedValues is the maximum number of allowed values that are listed in the error message
+const maxListedValues = 10
+
+// Error returns the error message
+//
+// implements error
+func (err InvalidEnumValueError) Error() string {
+	var message strings.Builder
+
+	if len(err.Values) == 1 {
+		value := err.Values[0]
+		if candidates, found := err.Candidates[value]; found {
+			message.WriteString(fmt.Sprintf("ambiguous value '%s'", value))
+			err.writeFlagName(&message)
+			message.WriteString(fmt.Sprintf(", could be %s", strings.Join(candidates, ", ")))
+			return message.String()
+		}
+		if core.Contains(err.NoMatch, value) {
+			message.WriteString(fmt.Sprintf("'%s' matches no value", value))
+			err.writeFlagName(&message)
+		} else {
+			message.WriteString(fmt.Sprintf("unknown value '%s'", value))
+			err.writeFlagName(&message)
+			if suggestions, found := err.Suggestions[value]; found {
+				message.WriteString(fmt.Sprintf(", did you mean '%s'?", strings.Join(suggestions, "' or '")))
+				return message.String()
+			}
+		}
+	} else {
+		message.WriteString("unknown values ")
+		for i, value := range err.Values {
+			if i > 0 {
+				message.WriteString(", ")
+			}
+			message.WriteString(fmt.Sprintf("'%s'", value))
+			if core.Contains(err.NoMatch, value) {
+				message.WriteString(" (matches no value)")
+			} else if candidates, found := err.Candidates[value]; found {
+				message.WriteString(fmt.Sprintf(" (could be %s)", strings.Join(candidates, ", ")))
+			} else if suggestions, found := err.Suggestions[value]; found {
+				message.WriteString(fmt.Sprintf(" (did you mean '%s'?)", strings.Join(suggestions, "' or '")))
+			}
+		}
+		err.writeFlagName(&message)
+	}
+	if len(err.Allowed) > 0 && len(err.Allowed) <= maxListedValues {
+		message.WriteString(fmt.Sprintf(", allowed values: %s", strings.Join(err.Allowed, ", ")))
+	}
+	return message.String()
+}
+
+// Unwrap returns the errors.ArgumentInvalid error this error stands for
+//
+// implements errors.Unwrap
+func (err InvalidEnumValueError) Unwrap() error {
+	return errors.ArgumentInvalid.With("value", strings.Join(err.Values, ","), strings.Join(err.Allowed, ", "))
+}
+
+// writeFlagName writes the flag name to the message, if known
+func (err InvalidEnumValueError) writeFlagName(message *strings.Builder) {
+	if len(err.FlagName) > 0 {
+		message.WriteString(fmt.Sprintf(" for flag --%s", err.FlagName))
+	}
+}
+
+// InvalidValueCountError is returned when an EnumSliceFlag has fewer values than its MinCount or more values than its MaxCount
+//
+// The error is an errors.ArgumentInvalid error.
+type InvalidValueCountError struct {
+	FlagName string   // The name of the flag
+	Values   []string // The values of the flag
+	MinCount int      // The minimum number of values, 0 if there is none
+	MaxCount int      // The maximum number of values, 0 if there is none
+}
+
+// Error returns the error message
+//
+// implements error
+func (err InvalidValueCountError) Error() string {
+	var message strings.Builder
+
+	message.WriteString("flag --" + err.FlagName + " needs ")
+	switch {
+	case err.MinCount > 0 && err.MaxCount > 0 && err.MinCount == err.MaxCount:
+		message.WriteString(fmt.Sprintf("exactly %s", plural(err.MinCount, "value")))
+	case err.MinCount > 0 && err.MaxCount > 0:
+		message.WriteString(fmt.Sprintf("between %d and %d values", err.MinCount, err.MaxCount))
+	case err.MinCount > 0:
+		message.WriteString(fmt.Sprintf("at least %s", plural(err.MinCount, "value")))
+	default:
+		message.WriteString(fmt.Sprintf("at most %s", plural(err.MaxCount, "value")))
+	}
+	message.WriteString(fmt.Sprintf(", got %d", len(err.Values)))
+	if len(err.Values) > 0 {
+		message.WriteString(fmt.Sprintf(" (%s)", strings.Join(err.Values, ", ")))
+	}
+	return message.String()
+}
+
+// Unwrap returns the errors.ArgumentInvalid error this error stands for
+//
+// implements errors.Unwrap
+func (err InvalidValueCountError) Unwrap() error {
+	return errors.ArgumentInvalid.With(err.FlagName, strings.Join(err.Values, ","))
+}
+
+// ConflictingValuesError is returned when an EnumSliceFlag gets values that cannot be combined (see EnumSliceFlag.Exclusive)
+//
+// The FlagName is set once the flag is known, i.e. when the flags are validated after the command line is parsed.
+//
+// The error is an errors.ArgumentInvalid error.
+type ConflictingValuesError struct {
+	FlagName string   // The name of the flag, if known
+	Values   []string // The values that cannot be combined
+}
+
+// Error returns the error message
+//
+// implements error
+func (err ConflictingValuesError) Error() string {
+	var message strings.Builder
+
+	message.WriteString(fmt.Sprintf("values '%s' cannot be combined", strings.Join(err.Values, "' and '")))
+	if len(err.FlagName) > 0 {
+		message.WriteString(fmt.Sprintf(" for flag --%s", err.FlagName))
+	}
+	return message.String()
+}
+
+// Unwrap returns the errors.ArgumentInvalid error this error stands for
+//
+// implements errors.Unwrap
+func (err ConflictingValuesError) Unwrap() error {
+	return errors.ArgumentInvalid.With("value", strings.Join(err.Values, ","))
+}
+
+// plural returns the count followed by the noun, in plural form if needed
+func plural(count int, noun string) string {
+	if count == 1 {
+		return fmt.Sprintf("%d %s", count, noun)
+	}
+	return fmt.Sprintf("%d %ss", count, noun)
+}
@@ -2,6 +2,7 @@ package flags_test
 
 import (
 	"context"
+	"encoding/csv"
 	"encoding/json"
 	"fmt"
 	"os"
@@ -16,6 +17,15 @@ import (
 	"github.com/joho/godotenv"
 	"github.com/spf13/cobra"
 	"github.com/stretchr/testify/suite"
+	"gopkg.in/yaml.v3"
+)
+
+type Format string
+
+const (
+	FormatJSON  Format = "json"
+	FormatYAML  Format = "yaml"
+	FormatTable Format = "table"
 )
 
 type FlagSuite struct {
@@ -105,7 +115,7 @@ func (suite *FlagSuite) NewCommand() *cobra.Command {
 		return nil
 	}}
 	cmd.SetContext(suite.Logger.ToContext(context.Background()))
-	return cmd
+	return flags.EnableValidation(cmd)
 }
 
 func (suite *FlagSuite) NewCommandWithSlice() *cobra.Command {
@@ -120,7 +130,7 @@ func (suite *FlagSuite) NewCommandWithSlice() *cobra.Command {
 		return nil
 	}}
 	cmd.SetContext(suite.Logger.ToContext(context.Background()))
-	return cmd
+	return flags.EnableValidation(cmd)
 }
 
 // *****************************************************************************
@@ -141,9 +151,9 @@ func (suite *FlagSuite) TestEnumFlag() {
 	suite.Require().NoError(err)
 	suite.Assert().Equal("one", output)
 
-	// See enum_flag.go for the commented code
-	// _, err = suite.Execute(root, "--state", "four")
-	// suite.Require().Error(err)
+	_, err = suite.Execute(root, "--state", "four")
+	suite.Require().Error(err, "four should not be allowed")
+	suite.Assert().ErrorIs(err, errors.ArgumentInvalid)
 }
 
 func (suite *FlagSuite) TestEnumFlagWithFunc() {
@@ -168,9 +178,40 @@ func (suite *FlagSuite) TestEnumFlagWithFunc() {
 	suite.Require().NoError(err)
 	suite.Assert().Equal("one", output)
 
-	// See enum_flag.go for the commented code
-	// _, err = suite.Execute(root, "--state", "four")
-	// suite.Require().Error(err)
+	_, err = suite.Execute(root, "--state", "four")
+	suite.Require().Error(err, "four should not be allowed")
+	suite.Assert().ErrorIs(err, errors.ArgumentInvalid)
+}
+
+func (suite *FlagSuite) TestEnumFlagShouldBeValidatedBeforePreRun() {
+	preRunCalled := false
+	root := &cobra.Command{
+		Use:  "root",
+		Args: cobra.NoArgs,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			preRunCalled = true
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error { return nil },
+	}
+	root.SetContext(suite.Logger.ToContext(context.Background()))
+	state := flags.NewEnumFlagWithFunc("one", func(context.Context, *cobra.Command, []string, string) ([]string, error) {
+		return []string{"one", "two", "three"}, nil
+	})
+	root.Flags().Var(state, "state", "State of the flag")
+	flags.EnableValidation(root)
+
+	_, err := suite.Execute(root, "--state", "four")
+	suite.Require().Error(err, "four should not be allowed")
+	suite.Assert().ErrorIs(err, errors.ArgumentInvalid)
+	suite.Assert().False(preRunCalled, "PreRunE should not be called when validation fails")
+
+	_, err = suite.Execute(root, "--state", "two", "extra")
+	suite.Require().Error(err, "the original Args validator should still be called")
+
+	_, err = suite.Execute(root, "--state", "two")
+	suite.Require().NoError(err)
+	suite.Assert().True(preRunCalled)
 }
 
 func (suite *FlagSuite) TestEnumFlagWithFuncReturningError() {
@@ -186,6 +227,48 @@ func (suite *FlagSuite) TestEnumFlagWithFuncReturningError() {
 	suite.Assert().Equal(":1\nCompletion ended with directive: ShellCompDirectiveError\n", output)
 }
 
+func (suite *FlagSuite) TestEnumFlagWithDescriptions() {
+	root := suite.NewCommand()
+	state := flags.NewEnumFlag("+one\tThe first state", "two\tThe second state", "three")
+	root.Flags().Var(state, "state", "State of the flag")
+	_ = root.RegisterFlagCompletionFunc(state.CompletionFunc("state"))
+
+	suite.Assert().Equal("one", state.Value)
+	suite.Assert().Equal([]string{"one", "two", "three"}, state.Allowed)
+
+	output, err := suite.Execute(root, "__complete", "--state", "")
+	suite.Require().NoError(err)
+	suite.Assert().Equal("one\tThe first state\ntwo\tThe second state\nthree\n:0\nCompletion ended with directive: ShellCompDirectiveDefault\n", output)
+
+	output, err = suite.Execute(root, "--state", "two")
+	suite.Require().NoError(err)
+	suite.Assert().Equal("two", output)
+}
+
+func (suite *FlagSuite) TestEnumFlagWithDescriptionFunc() {
+	root := suite.NewCommand()
+	state := flags.NewEnumFlagWithDescriptionFunc("one", func(context.Context, *cobra.Command, []string, string) ([]flags.AllowedValue, error) {
+		return []flags.AllowedValue{
+			{Value: "one", Description: "The first state"},
+			{Value: "two", Description: "The second state"},
+			{Value: "three"},
+		}, nil
+	})
+	root.Flags().Var(state, "state", "State of the flag")
+	_ = root.RegisterFlagCompletionFunc(state.CompletionFunc("state"))
+
+	output, err := suite.Execute(root, "__complete", "--state", "")
+	suite.Require().NoError(err)
+	suite.Assert().Equal("one\tThe first state\ntwo\tThe second state\nthree\n:0\nCompletion ended with directive: ShellCompDirectiveDefault\n", output)
+
+	output, err = suite.Execute(root, "--state", "three")
+	suite.Require().NoError(err)
+	suite.Assert().Equal("three", output)
+
+	_, err = suite.Execute(root, "--state", "four")
+	suite.Require().Error(err, "four should not be allowed")
+}
+
 func (suite *FlagSuite) TestEnumSliceFlag() {
 	root := suite.NewCommandWithSlice()
 	state := flags.NewEnumSliceFlag("+one", "+two", "three")
@@ -274,6 +357,43 @@ func (suite *FlagSuite) TestEnumSliceFlagWithAllAllowedCompletion() {
 	suite.Assert().Equal(":0\nCompletion ended with directive: ShellCompDirectiveDefault\n", output)
 }
 
+func (suite *FlagSuite) TestEnumSliceFlagWithDescriptions() {
+	root := suite.NewCommandWithSlice()
+	state := flags.NewEnumSliceFlagWithAllAllowed("+one\tThe first state", "two\tThe second state", "three")
+	root.Flags().Var(state, "state", "State of the flag")
+	_ = root.RegisterFlagCompletionFunc(state.CompletionFunc("state"))
+
+	suite.Assert().Equal([]string{"one"}, state.GetSlice())
+
+	output, err := suite.Execute(root, "__complete", "--state", "one", "--state", "")
+	suite.Require().NoError(err)
+	suite.Assert().Equal("two\tThe second state\nthree\nall\n:0\nCompletion ended with directive: ShellCompDirectiveDefault\n", output)
+}
+
+func (suite *FlagSuite) TestEnumSliceFlagWithDescriptionFunc() {
+	root := suite.NewCommandWithSlice()
+	state := flags.NewEnumSliceFlagWithAllAllowedAndDescriptionFunc(func(context.Context, *cobra.Command, []string, string) ([]flags.AllowedValue, error) {
+		return []flags.AllowedValue{
+			{Value: "one", Description: "The first state"},
+			{Value: "two", Description: "The second state"},
+			{Value: "three"},
+		}, nil
+	}, "one")
+	root.Flags().Var(state, "state", "State of the flag")
+	_ = root.RegisterFlagCompletionFunc(state.CompletionFunc("state"))
+
+	output, err := suite.Execute(root, "__complete", "--state", "")
+	suite.Require().NoError(err)
+	suite.Assert().Equal("one\tThe first state\ntwo\tThe second state\nthree\nall\n:0\nCompletion ended with directive: ShellCompDirectiveDefault\n", output)
+
+	output, err = suite.Execute(root, "--state", "all")
+	suite.Require().NoError(err)
+	suite.Assert().Equal("[one two three]", output)
+
+	_, err = suite.Execute(root, "--state", "four")
+	suite.Require().Error(err, "four should not be allowed")
+}
+
 func (suite *FlagSuite) TestEnumSliceFlagWithFuncShouldHaveDefault() {
 	root := suite.NewCommandWithSlice()
 	state := flags.NewEnumSliceFlagWithFunc(func(context.Context, *cobra.Command, []string, string) ([]string, error) {
@@ -330,7 +450,6 @@ func (suite *FlagSuite) TestEnumSliceFlagWithFuncShouldAcceptCommaSeparatedValue
 }
 
 func (suite *FlagSuite) TestEnumSliceFlagWithFuncShouldNotAcceptNonAllowedValues() {
-	suite.T().Skip("We cannot test this reliably yet (see bitbucket cli)")
 	root := suite.NewCommandWithSlice()
 	state := flags.NewEnumSliceFlagWithFunc(func(context.Context, *cobra.Command, []string, string) ([]string, error) {
 		return []string{"one", "two", "three"}, nil
@@ -340,6 +459,10 @@ func (suite *FlagSuite) TestEnumSliceFlagWithFuncShouldNotAcceptNonAllowedValues
 
 	_, err := suite.Execute(root, "--state", "four")
 	suite.Require().Error(err, "four should not be allowed")
+	suite.Assert().ErrorIs(err, errors.ArgumentInvalid)
+
+	_, err = suite.Execute(root, "--state", "one,four")
+	suite.Require().Error(err, "four should not be allowed")
 }
 
 func (suite *FlagSuite) TestEnumSliceFlagWithFuncShouldComplete() {
@@ -392,15 +515,12 @@ func (suite *FlagSuite) TestEnumSliceFlagWithAllAllowedAndFuncShouldAcceptAllAsV
 
 	output, err := suite.Execute(root, "--state", "all")
 	suite.Require().NoError(err)
-	// suite.Assert().Equal("[all one two three]", output)
-	suite.Assert().Equal("[all]", output)
+	suite.Assert().Equal("[one two three]", output)
 	values := state.GetSlice()
-	// suite.Assert().Equal([]string{"all", "one", "two", "three"}, values)
-	suite.Assert().Equal([]string{"all"}, values)
+	suite.Assert().Equal([]string{"all", "one", "two", "three"}, values)
 }
 
 func (suite *FlagSuite) TestEnumSliceFlagWithAllAllowedAndFuncNotAcceptNotAllowedValues() {
-	suite.T().Skip("We cannot test this reliably yet (see bitbucket cli)")
 	root := suite.NewCommandWithSlice()
 	state := flags.NewEnumSliceFlagWithAllAllowedAndFunc(func(context.Context, *cobra.Command, []string, string) ([]string, error) {
 		return []string{"one", "two", "three"}, nil
@@ -440,3 +560,1694 @@ func (suite *FlagSuite) TestEnumSliceFlagCanReplaceValues() {
 	values = state.GetSlice()
 	suite.Assert().Equal([]string{"one", "three"}, values)
 }
+
+func (suite *FlagSuite) TestTypedEnumFlag() {
+	root := suite.NewCommand()
+	format := flags.NewTypedEnumFlag(FormatJSON, FormatJSON, FormatYAML, FormatTable)
+	root.Flags().Var(format, "state", "Format of the output")
+	_ = root.RegisterFlagCompletionFunc(format.CompletionFunc("state"))
+
+	suite.Assert().Equal(FormatJSON, format.Get())
+
+	output, err := suite.Execute(root, "__complete", "--state", "")
+	suite.Require().NoError(err)
+	suite.Assert().Equal("json\nyaml\ntable\n:0\nCompletion ended with directive: ShellCompDirectiveDefault\n", output)
+
+	output, err = suite.Execute(root, "--state", "yaml")
+	suite.Require().NoError(err)
+	suite.Assert().Equal("yaml", output)
+	suite.Assert().Equal(FormatYAML, format.Get())
+
+	_, err = suite.Execute(root, "--state", "xml")
+	suite.Require().Error(err, "xml should not be allowed")
+}
+
+func (suite *FlagSuite) TestTypedEnumSliceFlag() {
+	root := suite.NewCommandWithSlice()
+	formats := flags.NewTypedEnumSliceFlagWithAllAllowed([]Format{FormatJSON}, FormatJSON, FormatYAML, FormatTable)
+	root.Flags().Var(formats, "state", "Formats of the output")
+	_ = root.RegisterFlagCompletionFunc(formats.CompletionFunc("state"))
+
+	suite.Assert().Equal([]Format{FormatJSON}, formats.Values())
+
+	output, err := suite.Execute(root, "--state", "yaml,table")
+	suite.Require().NoError(err)
+	suite.Assert().Equal("[yaml table]", output)
+	suite.Assert().Equal([]Format{FormatYAML, FormatTable}, formats.Values())
+
+	output, err = suite.Execute(root, "--state", "all")
+	suite.Require().NoError(err)
+	suite.Assert().Equal("[json yaml table]", output)
+	suite.Assert().Equal([]Format{FormatJSON, FormatYAML, FormatTable}, formats.Values())
+}
+
+func (suite *FlagSuite) TestTypedEnumSliceFlagCompletionWithValues() {
+	root := suite.NewCommandWithSlice()
+	formats := flags.NewTypedEnumSliceFlag([]Format{}, FormatJSON, FormatYAML, FormatTable)
+	root.Flags().Var(formats, "state", "Formats of the output")
+	_ = root.RegisterFlagCompletionFunc(formats.CompletionFunc("state"))
+
+	output, err := suite.Execute(root, "__complete", "--state", "json", "--state", "")
+	suite.Require().NoError(err)
+	suite.Assert().Equal("yaml\ntable\n:0\nCompletion ended with directive: ShellCompDirectiveDefault\n", output)
+
+	root = suite.NewCommandWithSlice()
+	formats = flags.NewTypedEnumSliceFlag([]Format{}, FormatJSON, FormatYAML, FormatTable)
+	formats.MaxCount = 1
+	root.Flags().Var(formats, "state", "Formats of the output")
+	_ = root.RegisterFlagCompletionFunc(formats.CompletionFunc("state"))
+
+	output, err = suite.Execute(root, "__complete", "--state", "json", "--state", "")
+	suite.Require().NoError(err)
+	suite.Assert().Equal(":4\nCompletion ended with directive: ShellCompDirectiveNoFileComp\n", output)
+}
+
+func (suite *FlagSuite) TestEnumFlagCaseInsensitive() {
+	root := suite.NewCommand()
+	state := flags.NewEnumFlag("+one", "two", "Three")
+	state.CaseInsensitive = true
+	root.Flags().Var(state, "state", "State of the flag")
+	_ = root.RegisterFlagCompletionFunc(state.CompletionFunc("state"))
+
+	output, err := suite.Execute(root, "--state", "TWO")
+	suite.Require().NoError(err)
+	suite.Assert().Equal("two", output)
+
+	output, err = suite.Execute(root, "--state", "three")
+	suite.Require().NoError(err)
+	suite.Assert().Equal("Three", output)
+
+	output, err = suite.Execute(root, "__complete", "--state", "T")
+	suite.Require().NoError(err)
+	suite.Assert().Equal("two\nThree\n:0\nCompletion ended with directive: ShellCompDirectiveDefault\n", output)
+
+	_, err = suite.Execute(root, "--state", "four")
+	suite.Require().Error(err, "four should not be allowed")
+}
+
+func (suite *FlagSuite) TestEnumFlagWithFuncCaseInsensitive() {
+	root := suite.NewCommand()
+	state := flags.NewEnumFlagWithFunc("one", func(context.Context, *cobra.Command, []string, string) ([]string, error) {
+		return []string{"one", "two", "three"}, nil
+	})
+	state.CaseInsensitive = true
+	root.Flags().Var(state, "state", "State of the flag")
+
+	output, err := suite.Execute(root, "--state", "One")
+	suite.Require().NoError(err)
+	suite.Assert().Equal("one", output)
+}
+
+func (suite *FlagSuite) TestEnumFlagShouldBeCaseSensitiveByDefault() {
+	root := suite.NewCommand()
+	state := flags.NewEnumFlag("+one", "two", "three")
+	root.Flags().Var(state, "state", "State of the flag")
+
+	_, err := suite.Execute(root, "--state", "TWO")
+	suite.Require().Error(err, "TWO should not be allowed")
+}
+
+func (suite *FlagSuite) TestEnumSliceFlagCaseInsensitive() {
+	root := suite.NewCommandWithSlice()
+	state := flags.NewEnumSliceFlagWithAllAllowed("+one", "two", "three")
+	state.CaseInsensitive = true
+	root.Flags().Var(state, "state", "State of the flag")
+	_ = root.RegisterFlagCompletionFunc(state.CompletionFunc("state"))
+
+	output, err := suite.Execute(root, "__complete", "--state", "A")
+	suite.Require().NoError(err)
+	suite.Assert().Equal("all\n:0\nCompletion ended with directive: ShellCompDirectiveDefault\n", output)
+
+	output, err = suite.Execute(root, "--state", "One,TWO", "--state", "two")
+	suite.Require().NoError(err)
+	suite.Assert().Equal("[one two]", output)
+	suite.Assert().Equal([]string{"one", "two"}, state.GetSlice())
+
+	output, err = suite.Execute(root, "--state", "ALL")
+	suite.Require().NoError(err)
+	suite.Assert().Equal("[one two three]", output)
+}
+
+func (suite *FlagSuite) TestEnumSliceFlagWithFuncCaseInsensitive() {
+	root := suite.NewCommandWithSlice()
+	state := flags.NewEnumSliceFlagWithFunc(func(context.Context, *cobra.Command, []string, string) ([]string, error) {
+		return []string{"one", "two", "three"}, nil
+	}, "one")
+	state.CaseInsensitive = true
+	root.Flags().Var(state, "state", "State of the flag")
+
+	output, err := suite.Execute(root, "--state", "THREE,One")
+	suite.Require().NoError(err)
+	suite.Assert().Equal("[three one]", output)
+}
+
+func (suite *FlagSuite) TestEnumFlagWithAliases() {
+	root := suite.NewCommand()
+	state := flags.NewEnumFlag("+json", "yaml", "table")
+	state.Aliases = map[string][]string{"yaml": {"yml"}}
+	root.Flags().Var(state, "state", "Format of the output")
+	_ = root.RegisterFlagCompletionFunc(state.CompletionFunc("state"))
+
+	output, err := suite.Execute(root, "--state", "yml")
+	suite.Require().NoError(err)
+	suite.Assert().Equal("yaml", output)
+	suite.Assert().Equal("yaml", state.String())
+
+	output, err = suite.Execute(root, "__complete", "--state", "y")
+	suite.Require().NoError(err)
+	suite.Assert().Equal("yaml\n:0\nCompletion ended with directive: ShellCompDirectiveDefault\n", output)
+
+	state.CompleteAliases = true
+	output, err = suite.Execute(root, "__complete", "--state", "y")
+	suite.Require().NoError(err)
+	suite.Assert().Equal("yaml\nyml\n:0\nCompletion ended with directive: ShellCompDirectiveDefault\n", output)
+}
+
+func (suite *FlagSuite) TestEnumFlagWithDescriptionFuncAndAliases() {
+	root := suite.NewCommand()
+	state := flags.NewEnumFlagWithDescriptionFunc("production", func(context.Context, *cobra.Command, []string, string) ([]flags.AllowedValue, error) {
+		return []flags.AllowedValue{
+			{Value: "development", Aliases: []string{"dev"}},
+			{Value: "production", Aliases: []string{"prod"}},
+		}, nil
+	})
+	state.CaseInsensitive = true
+	root.Flags().Var(state, "state", "Environment")
+
+	output, err := suite.Execute(root, "--state", "DEV")
+	suite.Require().NoError(err)
+	suite.Assert().Equal("development", output)
+}
+
+func (suite *FlagSuite) TestEnumSliceFlagWithAliases() {
+	root := suite.NewCommandWithSlice()
+	state := flags.NewEnumSliceFlag("+json", "yaml", "table")
+	state.Aliases = map[string][]string{"yaml": {"yml"}, "table": {"tbl", "text"}}
+	root.Flags().Var(state, "state", "Formats of the output")
+	_ = root.RegisterFlagCompletionFunc(state.CompletionFunc("state"))
+
+	output, err := suite.Execute(root, "--state", "yml,text", "--state", "yaml")
+	suite.Require().NoError(err)
+	suite.Assert().Equal("[yaml table]", output)
+	suite.Assert().Equal([]string{"yaml", "table"}, state.GetSlice())
+	suite.Assert().Equal("[yaml,table]", state.String())
+}
+
+func (suite *FlagSuite) TestEnumSliceFlagWithFuncAndAliases() {
+	root := suite.NewCommandWithSlice()
+	state := flags.NewEnumSliceFlagWithFunc(func(context.Context, *cobra.Command, []string, string) ([]string, error) {
+		return []string{"json", "yaml", "table"}, nil
+	})
+	state.Aliases = map[string][]string{"yaml": {"yml"}}
+	root.Flags().Var(state, "state", "Formats of the output")
+
+	output, err := suite.Execute(root, "--state", "yml,json")
+	suite.Require().NoError(err)
+	suite.Assert().Equal("[yaml json]", output)
+}
+
+func (suite *FlagSuite) TestEnumFlagWithAbbreviations() {
+	root := suite.NewCommand()
+	state := flags.NewEnumFlag("+one", "two", "three")
+	state.AbbreviationsAllowed = true
+	root.Flags().Var(state, "state", "State of the flag")
+
+	output, err := suite.Execute(root, "--state", "th")
+	suite.Require().NoError(err)
+	suite.Assert().Equal("three", output)
+
+	output, err = suite.Execute(root, "--state", "o")
+	suite.Require().NoError(err)
+	suite.Assert().Equal("one", output)
+
+	_, err = suite.Execute(root, "--state", "t")
+	suite.Require().Error(err, "t should be ambiguous")
+	suite.Assert().ErrorIs(err, errors.ArgumentInvalid)
+	suite.Assert().Contains(err.Error(), "two, three")
+}
+
+func (suite *FlagSuite) TestEnumFlagShouldNotAcceptAbbreviationsByDefault() {
+	root := suite.NewCommand()
+	state := flags.NewEnumFlag("+one", "two", "three")
+	root.Flags().Var(state, "state", "State of the flag")
+
+	_, err := suite.Execute(root, "--state", "th")
+	suite.Require().Error(err, "th should not be allowed")
+}
+
+func (suite *FlagSuite) TestEnumFlagWithFuncAndAbbreviations() {
+	root := suite.NewCommand()
+	state := flags.NewEnumFlagWithFunc("one", func(context.Context, *cobra.Command, []string, string) ([]string, error) {
+		return []string{"one", "two", "three"}, nil
+	})
+	state.AbbreviationsAllowed = true
+	root.Flags().Var(state, "state", "State of the flag")
+
+	output, err := suite.Execute(root, "--state", "tw")
+	suite.Require().NoError(err)
+	suite.Assert().Equal("two", output)
+}
+
+func (suite *FlagSuite) TestEnumSliceFlagWithAbbreviations() {
+	root := suite.NewCommandWithSlice()
+	state := flags.NewEnumSliceFlagWithAllAllowed("+one", "two", "three", "alpha")
+	state.AbbreviationsAllowed = true
+	root.Flags().Var(state, "state", "State of the flag")
+
+	output, err := suite.Execute(root, "--state", "o,tw", "--state", "th")
+	suite.Require().NoError(err)
+	suite.Assert().Equal("[one two three]", output)
+
+	_, err = suite.Execute(root, "--state", "one,t")
+	suite.Require().Error(err, "t should be ambiguous")
+
+	_, err = suite.Execute(root, "--state", "al")
+	suite.Require().Error(err, "al should be ambiguous")
+	suite.Assert().Contains(err.Error(), "alpha, all")
+
+	output, err = suite.Execute(root, "--state", "all")
+	suite.Require().NoError(err)
+	suite.Assert().Equal("[one two three alpha]", output)
+}
+
+func (suite *FlagSuite) TestEnumFlagShouldSuggestValues() {
+	root := suite.NewCommand()
+	state := flags.NewEnumFlag("+one", "two", "three")
+	root.Flags().Var(state, "state", "State of the flag")
+
+	_, err := suite.Execute(root, "--state", "thre")
+	suite.Require().Error(err, "thre should not be allowed")
+	suite.Assert().ErrorIs(err, errors.ArgumentInvalid)
+	suite.Assert().Contains(err.Error(), "did you mean 'three'?")
+
+	_, err = suite.Execute(root, "--state", "seventeen")
+	suite.Require().Error(err, "seventeen should not be allowed")
+	suite.Assert().NotContains(err.Error(), "did you mean")
+}
+
+func (suite *FlagSuite) TestEnumFlagWithFuncShouldSuggestValues() {
+	root := suite.NewCommand()
+	state := flags.NewEnumFlagWithFunc("one", func(context.Context, *cobra.Command, []string, string) ([]string, error) {
+		return []string{"one", "two", "three"}, nil
+	})
+	root.Flags().Var(state, "state", "State of the flag")
+
+	_, err := suite.Execute(root, "--state", "tow")
+	suite.Require().Error(err, "tow should not be allowed")
+	suite.Assert().Contains(err.Error(), "did you mean 'two'?")
+}
+
+func (suite *FlagSuite) TestEnumSliceFlagShouldSuggestValues() {
+	root := suite.NewCommandWithSlice()
+	state := flags.NewEnumSliceFlagWithAllAllowed("+one", "two", "three")
+	root.Flags().Var(state, "state", "State of the flag")
+
+	_, err := suite.Execute(root, "--state", "one,thre")
+	suite.Require().Error(err, "thre should not be allowed")
+	suite.Assert().ErrorIs(err, errors.ArgumentInvalid)
+	suite.Assert().Contains(err.Error(), "did you mean 'three'?")
+
+	_, err = suite.Execute(root, "--state", "al")
+	suite.Require().Error(err, "al should not be allowed")
+	suite.Assert().Contains(err.Error(), "did you mean 'all'?")
+}
+
+func (suite *FlagSuite) TestEnumFlagShouldReturnInvalidEnumValueError() {
+	root := suite.NewCommand()
+	state := flags.NewEnumFlagWithFunc("one", func(context.Context, *cobra.Command, []string, string) ([]string, error) {
+		return []string{"one", "two", "three"}, nil
+	})
+	root.Flags().Var(state, "state", "State of the flag")
+
+	_, err := suite.Execute(root, "--state", "thre")
+	suite.Require().Error(err, "thre should not be allowed")
+	suite.Assert().ErrorIs(err, errors.ArgumentInvalid)
+
+	var invalid *flags.InvalidEnumValueError
+	suite.Require().ErrorAs(err, &invalid)
+	suite.Assert().Equal("state", invalid.FlagName)
+	suite.Assert().Equal([]string{"thre"}, invalid.Values)
+	suite.Assert().Equal([]string{"one", "two", "three"}, invalid.Allowed)
+	suite.Assert().Equal([]string{"three"}, invalid.Suggestions["thre"])
+	suite.Assert().Equal("unknown value 'thre' for flag --state, did you mean 'three'?", err.Error())
+}
+
+func (suite *FlagSuite) TestEnumSliceFlagShouldReturnInvalidEnumValueError() {
+	root := suite.NewCommandWithSlice()
+	state := flags.NewEnumSliceFlagWithFunc(func(context.Context, *cobra.Command, []string, string) ([]string, error) {
+		return []string{"one", "two", "three"}, nil
+	})
+	root.Flags().Var(state, "state", "State of the flag")
+
+	_, err := suite.Execute(root, "--state", "one,thre,seventeen")
+	suite.Require().Error(err)
+	suite.Assert().ErrorIs(err, errors.ArgumentInvalid)
+
+	var invalid *flags.InvalidEnumValueError
+	suite.Require().ErrorAs(err, &invalid)
+	suite.Assert().Equal("state", invalid.FlagName)
+	suite.Assert().Equal([]string{"thre", "seventeen"}, invalid.Values)
+	suite.Assert().Equal([]string{"three"}, invalid.Suggestions["thre"])
+	suite.Assert().Empty(invalid.Suggestions["seventeen"])
+	suite.Assert().Equal("unknown values 'thre' (did you mean 'three'?), 'seventeen' for flag --state, allowed values: one, two, three", err.Error())
+}
+
+func (suite *FlagSuite) TestEnumSliceFlagShouldReturnInvalidEnumValueErrorWhileParsing() {
+	root := suite.NewCommandWithSlice()
+	state := flags.NewEnumSliceFlag("+one", "two", "three")
+	state.AbbreviationsAllowed = true
+	root.Flags().Var(state, "state", "State of the flag")
+
+	_, err := suite.Execute(root, "--state", "t")
+	suite.Require().Error(err)
+
+	var invalid *flags.InvalidEnumValueError
+	suite.Require().ErrorAs(err, &invalid)
+	suite.Assert().Equal([]string{"t"}, invalid.Values)
+	suite.Assert().Equal([]string{"two", "three"}, invalid.Candidates["t"])
+}
+
+func (suite *FlagSuite) TestEnumSliceFlagShouldShowDefaultsInHelp() {
+	root := suite.NewCommandWithSlice()
+	state := flags.NewEnumSliceFlag("+one", "+two", "three")
+	root.Flags().Var(state, "state", "State of the flag")
+
+	suite.Assert().Equal("[one,two]", state.String())
+
+	output, err := suite.Execute(root, "--help")
+	suite.Require().NoError(err)
+	suite.Assert().Contains(output, "State of the flag (default [one,two])")
+}
+
+func (suite *FlagSuite) TestEnumSliceFlagShouldGetDefaultsWhenNotSet() {
+	root := suite.NewCommandWithSlice()
+	state := flags.NewEnumSliceFlag("+one", "+two", "three")
+	root.Flags().Var(state, "state", "State of the flag")
+
+	output, err := suite.Execute(root)
+	suite.Require().NoError(err)
+	suite.Assert().Equal("[one two]", output)
+}
+
+func (suite *FlagSuite) TestEnumFlagsShouldShowAllowedInHelp() {
+	root := flags.EnableAllowedInHelp(suite.NewCommandWithSlice())
+	state := flags.NewEnumSliceFlagWithAllAllowed("+one", "+two", "three")
+	root.Flags().Var(state, "state", "State of the flag")
+	format := flags.NewEnumFlagWithFunc("json", func(context.Context, *cobra.Command, []string, string) ([]string, error) {
+		return []string{"json", "yaml"}, nil
+	})
+	root.PersistentFlags().Var(format, "format", "Output format")
+	child := &cobra.Command{Use: "child", RunE: func(cmd *cobra.Command, args []string) error { return nil }}
+	root.AddCommand(child)
+
+	output, err := suite.Execute(root, "--help")
+	suite.Require().NoError(err)
+	suite.Assert().Contains(output, "State of the flag (one|two|three|all) (default [one,two])")
+	suite.Assert().Contains(output, "Output format (json|yaml) (default \"json\")")
+
+	output, err = suite.Execute(root, "child", "--help")
+	suite.Require().NoError(err)
+	suite.Assert().Contains(output, "Output format (json|yaml) (default \"json\")")
+	suite.Assert().NotContains(output, "(json|yaml) (json|yaml)")
+}
+
+func (suite *FlagSuite) TestEnumSliceFlagCanRemoveValuesFromDefaults() {
+	root := suite.NewCommandWithSlice()
+	state := flags.NewEnumSliceFlag("+one", "+two", "three")
+	root.Flags().Var(state, "state", "State of the flag")
+
+	output, err := suite.Execute(root, "--state", "-two")
+	suite.Require().NoError(err)
+	suite.Assert().Equal("[one]", output)
+	suite.Assert().Equal([]string{"one"}, state.GetSlice())
+
+	root = suite.NewCommandWithSlice()
+	state = flags.NewEnumSliceFlag("+one", "+two", "three")
+	root.Flags().Var(state, "state", "State of the flag")
+
+	output, err = suite.Execute(root, "--state", "!one,three", "--state=-two")
+	suite.Require().NoError(err)
+	suite.Assert().Equal("[three]", output)
+
+	root = suite.NewCommandWithSlice()
+	state = flags.NewEnumSliceFlag("+one", "+two", "three")
+	root.Flags().Var(state, "state", "State of the flag")
+
+	output, err = suite.Execute(root, "--state", "-one,-two")
+	suite.Require().NoError(err)
+	suite.Assert().Equal("[]", output)
+	suite.Assert().Empty(state.GetSlice())
+}
+
+func (suite *FlagSuite) TestEnumSliceFlagCanRemoveValuesFromAll() {
+	root := suite.NewCommandWithSlice()
+	state := flags.NewEnumSliceFlagWithAllAllowed("+one", "two", "three")
+	root.Flags().Var(state, "state", "State of the flag")
+
+	output, err := suite.Execute(root, "--state", "all,-three")
+	suite.Require().NoError(err)
+	suite.Assert().Equal("[one two]", output)
+	suite.Assert().Equal([]string{"one", "two"}, state.GetSlice())
+}
+
+func (suite *FlagSuite) TestEnumSliceFlagWithFuncCanRemoveValues() {
+	root := suite.NewCommandWithSlice()
+	state := flags.NewEnumSliceFlagWithAllAllowedAndFunc(func(context.Context, *cobra.Command, []string, string) ([]string, error) {
+		return []string{"one", "two", "three"}, nil
+	}, "one", "two")
+	root.Flags().Var(state, "state", "State of the flag")
+
+	output, err := suite.Execute(root, "--state", "all", "--state", "-one")
+	suite.Require().NoError(err)
+	suite.Assert().Equal("[two three]", output)
+
+	root = suite.NewCommandWithSlice()
+	state = flags.NewEnumSliceFlagWithFunc(func(context.Context, *cobra.Command, []string, string) ([]string, error) {
+		return []string{"one", "two", "three"}, nil
+	}, "one", "two")
+	root.Flags().Var(state, "state", "State of the flag")
+
+	output, err = suite.Execute(root, "--state", "-one")
+	suite.Require().NoError(err)
+	suite.Assert().Equal("[two]", output)
+
+	_, err = suite.Execute(root, "--state", "-four")
+	suite.Require().Error(err, "four should not be allowed")
+}
+
+func (suite *FlagSuite) NewServicesFlag() *flags.EnumSliceFlag {
+	services := flags.NewEnumSliceFlagWithAllAllowed("api", "worker", "db", "web", "cdn")
+	services.Groups = map[string][]string{
+		"@backend":  {"api", "worker", "db"},
+		"@frontend": {"web", "cdn"},
+	}
+	return services
+}
+
+func (suite *FlagSuite) TestEnumSliceFlagWithGroups() {
+	root := suite.NewCommandWithSlice()
+	state := suite.NewServicesFlag()
+	root.Flags().Var(state, "state", "Services")
+
+	output, err := suite.Execute(root, "--state", "@frontend,db")
+	suite.Require().NoError(err)
+	suite.Assert().Equal("[web cdn db]", output)
+	suite.Assert().Equal([]string{"@frontend", "web", "cdn", "db"}, state.GetSlice())
+}
+
+func (suite *FlagSuite) TestEnumSliceFlagWithGroupsAndExclusions() {
+	root := suite.NewCommandWithSlice()
+	state := suite.NewServicesFlag()
+	root.Flags().Var(state, "state", "Services")
+
+	output, err := suite.Execute(root, "--state", "all,-@frontend")
+	suite.Require().NoError(err)
+	suite.Assert().Equal("[api worker db]", output)
+	suite.Assert().Equal([]string{"api", "worker", "db"}, state.GetSlice())
+
+	root = suite.NewCommandWithSlice()
+	state = suite.NewServicesFlag()
+	root.Flags().Var(state, "state", "Services")
+
+	output, err = suite.Execute(root, "--state", "@backend,@frontend,-db")
+	suite.Require().NoError(err)
+	suite.Assert().Equal("[api worker web cdn]", output)
+	suite.Assert().Equal([]string{"@frontend", "api", "worker", "web", "cdn"}, state.GetSlice())
+}
+
+func (suite *FlagSuite) TestEnumSliceFlagWithGroupsCompletion() {
+	root := suite.NewCommandWithSlice()
+	state := suite.NewServicesFlag()
+	root.Flags().Var(state, "state", "Services")
+	_ = root.RegisterFlagCompletionFunc(state.CompletionFunc("state"))
+
+	output, err := suite.Execute(root, "__complete", "--state", "")
+	suite.Require().NoError(err)
+	suite.Assert().Equal("api\nworker\ndb\nweb\ncdn\n@backend\tapi, worker, db\n@frontend\tweb, cdn\nall\n:0\nCompletion ended with directive: ShellCompDirectiveDefault\n", output)
+
+	output, err = suite.Execute(root, "__complete", "--state", "@backend", "--state", "@")
+	suite.Require().NoError(err)
+	suite.Assert().Equal("@frontend\tweb, cdn\n:0\nCompletion ended with directive: ShellCompDirectiveDefault\n", output)
+}
+
+func (suite *FlagSuite) TestEnumSliceFlagWithFuncAndGroups() {
+	root := suite.NewCommandWithSlice()
+	state := flags.NewEnumSliceFlagWithFunc(func(context.Context, *cobra.Command, []string, string) ([]string, error) {
+		return []string{"api", "worker", "db", "web", "cdn"}, nil
+	})
+	state.Groups = map[string][]string{"@backend": {"api", "worker", "db"}}
+	root.Flags().Var(state, "state", "Services")
+
+	output, err := suite.Execute(root, "--state", "@backend,web")
+	suite.Require().NoError(err)
+	suite.Assert().Equal("[api worker db web]", output)
+	suite.Assert().Equal([]string{"@backend", "api", "worker", "db", "web"}, state.GetSlice())
+
+	_, err = suite.Execute(root, "--state", "@backnd")
+	suite.Require().Error(err)
+	suite.Assert().Contains(err.Error(), "did you mean '@backend'?")
+}
+
+func (suite *FlagSuite) NewServiceNamesFlag() *flags.EnumSliceFlag {
+	services := flags.NewEnumSliceFlag("svc.billing.api", "svc.billing.worker", "svc.users.api", "web-staging", "web-production", "db-staging")
+	services.PatternsAllowed = true
+	return services
+}
+
+func (suite *FlagSuite) TestEnumSliceFlagWithPatterns() {
+	root := suite.NewCommandWithSlice()
+	state := suite.NewServiceNamesFlag()
+	root.Flags().Var(state, "state", "Services")
+
+	output, err := suite.Execute(root, "--state", "svc.billing.*", "--state", "*-staging")
+	suite.Require().NoError(err)
+	suite.Assert().Equal("[svc.billing.api svc.billing.worker web-staging db-staging]", output)
+
+	root = suite.NewCommandWithSlice()
+	state = suite.NewServiceNamesFlag()
+	root.Flags().Var(state, "state", "Services")
+
+	output, err = suite.Execute(root, "--state", "svc.*,-*.worker")
+	suite.Require().NoError(err)
+	suite.Assert().Equal("[svc.billing.api svc.users.api]", output)
+
+	_, err = suite.Execute(root, "--state", "svc.orders.*")
+	suite.Require().Error(err, "svc.orders.* should not match anything")
+	suite.Assert().ErrorIs(err, errors.ArgumentInvalid)
+	suite.Assert().Contains(err.Error(), "'svc.orders.*' matches no value")
+}
+
+func (suite *FlagSuite) TestEnumSliceFlagShouldNotAcceptPatternsByDefault() {
+	root := suite.NewCommandWithSlice()
+	state := flags.NewEnumSliceFlag("svc.billing.api", "svc.billing.worker")
+	root.Flags().Var(state, "state", "Services")
+
+	_, err := suite.Execute(root, "--state", "svc.billing.*")
+	suite.Require().Error(err, "patterns should not be allowed")
+}
+
+func (suite *FlagSuite) TestEnumSliceFlagWithPatternsCompletion() {
+	root := suite.NewCommandWithSlice()
+	state := suite.NewServiceNamesFlag()
+	root.Flags().Var(state, "state", "Services")
+	_ = root.RegisterFlagCompletionFunc(state.CompletionFunc("state"))
+
+	output, err := suite.Execute(root, "__complete", "--state", "svc.b*.a")
+	suite.Require().NoError(err)
+	suite.Assert().Equal("svc.billing.api\n:4\nCompletion ended with directive: ShellCompDirectiveNoFileComp\n", output)
+
+	output, err = suite.Execute(root, "__complete", "--state", "svc.*")
+	suite.Require().NoError(err)
+	suite.Assert().Equal("svc.billing.api\nsvc.billing.worker\nsvc.users.api\n:4\nCompletion ended with directive: ShellCompDirectiveNoFileComp\n", output)
+
+	output, err = suite.Execute(root, "__complete", "--state", "*-staging")
+	suite.Require().NoError(err)
+	suite.Assert().Equal(":4\nCompletion ended with directive: ShellCompDirectiveNoFileComp\n", output)
+
+	output, err = suite.Execute(root, "__complete", "--state", "web")
+	suite.Require().NoError(err)
+	suite.Assert().Equal("web-staging\nweb-production\n:0\nCompletion ended with directive: ShellCompDirectiveDefault\n", output)
+}
+
+func (suite *FlagSuite) NewLevelsFlag() *flags.EnumSliceFlag {
+	levels := flags.NewEnumSliceFlag("trace", "debug", "info", "warn", "error", "fatal")
+	levels.Ordered = true
+	return levels
+}
+
+func (suite *FlagSuite) TestEnumSliceFlagWithRanges() {
+	root := suite.NewCommandWithSlice()
+	state := suite.NewLevelsFlag()
+	root.Flags().Var(state, "state", "Levels")
+
+	output, err := suite.Execute(root, "--state", "info..error")
+	suite.Require().NoError(err)
+	suite.Assert().Equal("[info warn error]", output)
+
+	root = suite.NewCommandWithSlice()
+	state = suite.NewLevelsFlag()
+	root.Flags().Var(state, "state", "Levels")
+
+	output, err = suite.Execute(root, "--state", "error..info")
+	suite.Require().NoError(err)
+	suite.Assert().Equal("[info warn error]", output)
+
+	_, err = suite.Execute(root, "--state", "info..eror")
+	suite.Require().Error(err, "eror is not an allowed value")
+	suite.Assert().ErrorIs(err, errors.ArgumentInvalid)
+}
+
+func (suite *FlagSuite) TestEnumSliceFlagWithComparisons() {
+	testCases := map[string]string{
+		">=warn":               "[warn error fatal]",
+		">warn":                "[error fatal]",
+		"<=info":               "[trace debug info]",
+		"<info":                "[trace debug]",
+		">=debug,-warn..error": "[debug info fatal]",
+	}
+	for value, expected := range testCases {
+		root := suite.NewCommandWithSlice()
+		state := suite.NewLevelsFlag()
+		root.Flags().Var(state, "state", "Levels")
+
+		output, err := suite.Execute(root, "--state", value)
+		suite.Require().NoErrorf(err, "Failed to parse %s", value)
+		suite.Assert().Equalf(expected, output, "Invalid values for %s", value)
+	}
+
+	root := suite.NewCommandWithSlice()
+	state := suite.NewLevelsFlag()
+	root.Flags().Var(state, "state", "Levels")
+
+	_, err := suite.Execute(root, "--state", ">fatal")
+	suite.Require().Error(err, "nothing comes after fatal")
+	suite.Assert().ErrorIs(err, errors.ArgumentInvalid)
+	suite.Assert().Contains(err.Error(), "'>fatal' matches no value, allowed values: trace, debug, info, warn, error, fatal")
+
+	_, err = suite.Execute(root, "--state", "<trace,>eror")
+	suite.Require().Error(err, "nothing comes before trace and eror is not an allowed value")
+	suite.Assert().Contains(err.Error(), "unknown values '<trace' (matches no value), '>eror' (did you mean 'error'?)")
+}
+
+func (suite *FlagSuite) TestEnumSliceFlagShouldNotAcceptRangesByDefault() {
+	root := suite.NewCommandWithSlice()
+	state := flags.NewEnumSliceFlag("debug", "info", "warn")
+	root.Flags().Var(state, "state", "Levels")
+
+	_, err := suite.Execute(root, "--state", "debug..warn")
+	suite.Require().Error(err, "ranges should not be allowed")
+}
+
+func (suite *FlagSuite) TestEnumSliceFlagWithFuncAndRanges() {
+	root := suite.NewCommandWithSlice()
+	state := flags.NewEnumSliceFlagWithFunc(func(context.Context, *cobra.Command, []string, string) ([]string, error) {
+		return []string{"debug", "info", "warn", "error"}, nil
+	})
+	state.Ordered = true
+	root.Flags().Var(state, "state", "Levels")
+
+	output, err := suite.Execute(root, "--state", ">=info")
+	suite.Require().NoError(err)
+	suite.Assert().Equal("[info warn error]", output)
+	suite.Assert().True(state.AtLeast("info"))
+	suite.Assert().False(state.AtLeast("warn"))
+	suite.Assert().True(state.AtMost("error"))
+}
+
+func (suite *FlagSuite) TestEnumFlagCanCompareValues() {
+	root := suite.NewCommand()
+	level := flags.NewEnumFlag("debug", "+info", "warn", "error")
+	root.Flags().Var(level, "state", "Level")
+
+	suite.Assert().True(level.AtLeast("info"))
+	suite.Assert().False(level.AtLeast("warn"))
+	suite.Assert().True(level.AtMost("warn"))
+	suite.Assert().False(level.AtLeast("unknown"))
+
+	_, err := suite.Execute(root, "--state", "error")
+	suite.Require().NoError(err)
+	suite.Assert().True(level.AtLeast("warn"))
+	suite.Assert().False(level.AtMost("warn"))
+}
+
+func (suite *FlagSuite) TestEnumFlagWithFuncCanCompareValues() {
+	root := suite.NewCommand()
+	level := flags.NewEnumFlagWithFunc("info", func(context.Context, *cobra.Command, []string, string) ([]string, error) {
+		return []string{"debug", "info", "warn", "error"}, nil
+	})
+	root.Flags().Var(level, "state", "Level")
+
+	_, err := suite.Execute(root, "--state", "warn")
+	suite.Require().NoError(err)
+	suite.Assert().True(level.AtLeast("info"))
+	suite.Assert().True(level.AtMost("warn"))
+	suite.Assert().False(level.AtLeast("error"))
+}
+
+func (suite *FlagSuite) NewRegionsCommand() (*cobra.Command, *flags.EnumSliceFlag) {
+	root := suite.NewCommandWithSlice()
+	regions := flags.NewEnumSliceFlagWithAllAllowed("us-east", "us-west", "eu-west", "eu-north", "ap-south")
+	regions.MinCount = 1
+	regions.MaxCount = 3
+	root.Flags().Var(regions, "state", "Regions")
+	_ = root.RegisterFlagCompletionFunc(regions.CompletionFunc("state"))
+	return root, regions
+}
+
+func (suite *FlagSuite) TestEnumSliceFlagWithCount() {
+	root, _ := suite.NewRegionsCommand()
+	output, err := suite.Execute(root, "--state", "us-east,eu-west")
+	suite.Require().NoError(err)
+	suite.Assert().Equal("[us-east eu-west]", output)
+
+	root, _ = suite.NewRegionsCommand()
+	_, err = suite.Execute(root)
+	suite.Require().Error(err, "at least one region is needed")
+	suite.Assert().ErrorIs(err, errors.ArgumentInvalid)
+	suite.Assert().Equal("flag --state needs between 1 and 3 values, got 0", err.Error())
+
+	root, _ = suite.NewRegionsCommand()
+	_, err = suite.Execute(root, "--state", "us-east,us-west", "--state", "eu-west,eu-north")
+	suite.Require().Error(err, "at most three regions are allowed")
+	var invalid *flags.InvalidValueCountError
+	suite.Require().ErrorAs(err, &invalid)
+	suite.Assert().Equal("state", invalid.FlagName)
+	suite.Assert().Equal([]string{"us-east", "us-west", "eu-west", "eu-north"}, invalid.Values)
+
+	root, _ = suite.NewRegionsCommand()
+	_, err = suite.Execute(root, "--state", "all")
+	suite.Require().Error(err, "all gives too many regions")
+}
+
+func (suite *FlagSuite) TestEnumSliceFlagWithMinCountShouldCountDefaults() {
+	root := suite.NewCommandWithSlice()
+	state := flags.NewEnumSliceFlag("+one", "+two", "three")
+	state.MinCount = 2
+	root.Flags().Var(state, "state", "State of the flag")
+
+	output, err := suite.Execute(root)
+	suite.Require().NoError(err)
+	suite.Assert().Equal("[one two]", output)
+
+	_, err = suite.Execute(root, "--state", "-one")
+	suite.Require().Error(err)
+	suite.Assert().Equal("flag --state needs at least 2 values, got 1 (two)", err.Error())
+}
+
+func (suite *FlagSuite) TestEnumSliceFlagWithMaxCountCompletion() {
+	root, _ := suite.NewRegionsCommand()
+	output, err := suite.Execute(root, "__complete", "--state", "us-east", "--state", "eu")
+	suite.Require().NoError(err)
+	suite.Assert().Equal("eu-west\neu-north\n:0\nCompletion ended with directive: ShellCompDirectiveDefault\n", output)
+
+	root, _ = suite.NewRegionsCommand()
+	output, err = suite.Execute(root, "__complete", "--state", "us-east", "--state", "us-west", "--state", "eu-west", "--state", "")
+	suite.Require().NoError(err)
+	suite.Assert().Equal(":4\nCompletion ended with directive: ShellCompDirectiveNoFileComp\n", output)
+}
+
+func (suite *FlagSuite) NewOutputsCommand() (*cobra.Command, *flags.EnumSliceFlag) {
+	root := suite.NewCommandWithSlice()
+	outputs := flags.NewEnumSliceFlag("+table", "json", "yaml", "color", "none")
+	outputs.Exclusive = [][]string{{"json", "yaml", "table"}, {"none"}}
+	root.Flags().Var(outputs, "state", "Outputs")
+	_ = root.RegisterFlagCompletionFunc(outputs.CompletionFunc("state"))
+	return root, outputs
+}
+
+func (suite *FlagSuite) TestEnumSliceFlagWithExclusiveValues() {
+	root, _ := suite.NewOutputsCommand()
+	output, err := suite.Execute(root, "--state", "json,color")
+	suite.Require().NoError(err)
+	suite.Assert().Equal("[json color]", output)
+
+	root, _ = suite.NewOutputsCommand()
+	_, err = suite.Execute(root, "--state", "json,table")
+	suite.Require().Error(err, "json and table cannot be combined")
+	suite.Assert().ErrorIs(err, errors.ArgumentInvalid)
+	var conflicting *flags.ConflictingValuesError
+	suite.Require().ErrorAs(err, &conflicting)
+	suite.Assert().Equal([]string{"json", "table"}, conflicting.Values)
+
+	root, _ = suite.NewOutputsCommand()
+	_, err = suite.Execute(root, "--state", "color", "--state", "none")
+	suite.Require().Error(err, "none cannot be combined with anything")
+	suite.Assert().Contains(err.Error(), "values 'color' and 'none' cannot be combined")
+
+	root, _ = suite.NewOutputsCommand()
+	output, err = suite.Execute(root, "--state", "none")
+	suite.Require().NoError(err)
+	suite.Assert().Equal("[none]", output)
+}
+
+func (suite *FlagSuite) TestEnumSliceFlagWithFuncAndExclusiveValues() {
+	root := suite.NewCommandWithSlice()
+	outputs := flags.NewEnumSliceFlagWithFunc(func(context.Context, *cobra.Command, []string, string) ([]string, error) {
+		return []string{"json", "yaml", "color"}, nil
+	})
+	outputs.Exclusive = [][]string{{"json", "yaml"}}
+	root.Flags().Var(outputs, "state", "Outputs")
+
+	_, err := suite.Execute(root, "--state", "json", "--state", "yaml")
+	suite.Require().Error(err, "json and yaml cannot be combined")
+	suite.Assert().Equal("values 'json' and 'yaml' cannot be combined for flag --state", err.Error())
+}
+
+func (suite *FlagSuite) TestEnumSliceFlagWithExclusiveValuesCompletion() {
+	root, _ := suite.NewOutputsCommand()
+	output, err := suite.Execute(root, "__complete", "--state", "json", "--state", "")
+	suite.Require().NoError(err)
+	suite.Assert().Equal("color\n:0\nCompletion ended with directive: ShellCompDirectiveDefault\n", output)
+}
+
+func (suite *FlagSuite) NewCommandWithRules() *cobra.Command {
+	root := suite.NewCommand()
+	root.Flags().Var(flags.NewEnumFlag("+open", "closed"), "state", "State of the issue")
+	root.Flags().Var(flags.NewEnumSliceFlag("+json", "table", "yaml"), "format", "Formats")
+	root.Flags().String("assignee", "", "Assignee")
+	root.Flags().StringSlice("columns", []string{}, "Columns")
+	return flags.AddRules(root, flags.Requires("format", "table", "columns"), flags.Forbids("state", "closed", "assignee"))
+}
+
+func (suite *FlagSuite) TestCommandWithRules() {
+	root := suite.NewCommandWithRules()
+	output, err := suite.Execute(root, "--state", "closed", "--format", "table", "--columns", "id,title")
+	suite.Require().NoError(err)
+	suite.Assert().Equal("closed", output)
+
+	root = suite.NewCommandWithRules()
+	output, err = suite.Execute(root, "--assignee", "john")
+	suite.Require().NoError(err)
+	suite.Assert().Equal("open", output)
+}
+
+func (suite *FlagSuite) TestCommandWithRulesShouldFailWhenRequiredFlagIsMissing() {
+	root := suite.NewCommandWithRules()
+	_, err := suite.Execute(root, "--format", "json,table")
+	suite.Require().Error(err, "--columns is required with --format table")
+	suite.Assert().ErrorIs(err, errors.ArgumentMissing)
+	suite.Assert().Equal("flag --columns is required when --format is table", err.Error())
+}
+
+func (suite *FlagSuite) TestCommandWithRulesShouldFailWhenForbiddenFlagIsGiven() {
+	root := suite.NewCommandWithRules()
+	_, err := suite.Execute(root, "--state", "closed", "--assignee", "john")
+	suite.Require().Error(err, "--assignee is forbidden with --state closed")
+	suite.Assert().ErrorIs(err, errors.ArgumentInvalid)
+	var ruleError *flags.RuleError
+	suite.Require().ErrorAs(err, &ruleError)
+	suite.Assert().Equal("state", ruleError.Rule.FlagName)
+	suite.Assert().Equal("assignee", ruleError.Rule.OtherFlagName)
+	suite.Assert().Equal("flag --assignee cannot be used when --state is closed", err.Error())
+}
+
+func (suite *FlagSuite) NewCloudCommand() *cobra.Command {
+	root := suite.NewCommand()
+	root.Flags().Var(flags.NewEnumFlag("+aws", "gcp"), "cloud", "Cloud")
+	region := flags.NewEnumFlagWithFunc("", flags.DependsOn("cloud", map[string][]string{
+		"aws": {"us-east-1", "eu-west-1"},
+		"gcp": {"us-central1", "europe-west1"},
+	}))
+	root.Flags().Var(region, "state", "Region")
+	_ = root.RegisterFlagCompletionFunc(region.CompletionFunc("state"))
+	return root
+}
+
+func (suite *FlagSuite) TestEnumFlagDependingOnAnotherFlag() {
+	root := suite.NewCloudCommand()
+	output, err := suite.Execute(root, "--cloud", "gcp", "--state", "europe-west1")
+	suite.Require().NoError(err)
+	suite.Assert().Equal("europe-west1", output)
+
+	root = suite.NewCloudCommand()
+	output, err = suite.Execute(root, "--state", "eu-west-1")
+	suite.Require().NoError(err, "aws is the default cloud")
+	suite.Assert().Equal("eu-west-1", output)
+
+	root = suite.NewCloudCommand()
+	_, err = suite.Execute(root, "--cloud", "aws", "--state", "europe-west1")
+	suite.Require().Error(err, "europe-west1 is not an aws region")
+	suite.Assert().Equal("unknown value 'europe-west1' for flag --state, allowed values: us-east-1, eu-west-1", err.Error())
+}
+
+func (suite *FlagSuite) TestEnumFlagDependingOnAnotherFlagShouldNotValidateDefault() {
+	root := suite.NewCommand()
+	root.Flags().Var(flags.NewEnumFlag("+aws", "gcp"), "cloud", "Cloud")
+	region := flags.NewEnumFlagWithFunc("us-east-1", flags.DependsOn("cloud", map[string][]string{
+		"aws": {"us-east-1", "eu-west-1"},
+		"gcp": {"us-central1", "europe-west1"},
+	}))
+	root.Flags().Var(region, "state", "Region")
+
+	output, err := suite.Execute(root, "--cloud", "gcp")
+	suite.Require().NoError(err, "the default value of a dependent flag is not validated")
+	suite.Assert().Equal("us-east-1", output)
+}
+
+func (suite *FlagSuite) TestEnumFlagDependingOnAnotherFlagCompletion() {
+	root := suite.NewCloudCommand()
+	output, err := suite.Execute(root, "__complete", "--cloud", "gcp", "--state", "")
+	suite.Require().NoError(err)
+	suite.Assert().Equal("us-central1\neurope-west1\n:0\nCompletion ended with directive: ShellCompDirectiveDefault\n", output)
+}
+
+func (suite *FlagSuite) NewCloudsCommand() *cobra.Command {
+	root := suite.NewCommandWithSlice()
+	root.Flags().Var(flags.NewEnumSliceFlag("aws", "gcp"), "cloud", "Clouds")
+	regions := flags.NewEnumSliceFlagWithFunc(flags.DependsOnFunc("cloud", func(ctx context.Context, cloud string) ([]string, error) {
+		switch cloud {
+		case "aws":
+			return []string{"us-east-1", "eu-west-1"}, nil
+		case "gcp":
+			return []string{"us-central1", "europe-west1"}, nil
+		}
+		return []string{}, nil
+	}))
+	root.Flags().Var(regions, "state", "Regions")
+	return root
+}
+
+func (suite *FlagSuite) TestEnumSliceFlagDependingOnAnotherSliceFlag() {
+	root := suite.NewCloudsCommand()
+	output, err := suite.Execute(root, "--cloud", "aws,gcp", "--state", "us-east-1,europe-west1")
+	suite.Require().NoError(err)
+	suite.Assert().Equal("[us-east-1 europe-west1]", output)
+
+	root = suite.NewCloudsCommand()
+	_, err = suite.Execute(root, "--state", "us-east-1")
+	suite.Require().Error(err, "no cloud gives no region")
+}
+
+func (suite *FlagSuite) TestEnumFlagFromEnvironment() {
+	suite.T().Setenv("TEST_STATE", "two")
+	root := suite.NewCommand()
+	state := flags.NewEnumFlag("+one", "two", "three")
+	root.Flags().Var(state, "state", "State of the flag")
+	flags.EnableEnvironment(root, "test")
+
+	suite.Assert().Equal("TEST_STATE", state.EnvVar)
+	output, err := suite.Execute(root)
+	suite.Require().NoError(err)
+	suite.Assert().Equal("two", output)
+	suite.Assert().True(state.FromEnvironment())
+}
+
+func (suite *FlagSuite) TestEnumFlagFromEnvironmentShouldNotOverrideCommandLine() {
+	suite.T().Setenv("TEST_STATE", "two")
+	root := suite.NewCommand()
+	state := flags.NewEnumFlag("+one", "two", "three")
+	state.EnvVar = "TEST_STATE"
+	root.Flags().Var(state, "state", "State of the flag")
+
+	output, err := suite.Execute(root, "--state", "three")
+	suite.Require().NoError(err)
+	suite.Assert().Equal("three", output)
+	suite.Assert().False(state.FromEnvironment())
+}
+
+func (suite *FlagSuite) TestEnumFlagFromEnvironmentWithInvalidValue() {
+	suite.T().Setenv("TEST_STATE", "four")
+	root := suite.NewCommand()
+	state := flags.NewEnumFlagWithFunc("one", func(context.Context, *cobra.Command, []string, string) ([]string, error) {
+		return []string{"one", "two", "three"}, nil
+	})
+	state.EnvVar = "TEST_STATE"
+	root.Flags().Var(state, "state", "State of the flag")
+
+	_, err := suite.Execute(root)
+	suite.Require().Error(err, "four is not allowed")
+	var invalid *flags.InvalidEnumValueError
+	suite.Require().ErrorAs(err, &invalid)
+	suite.Assert().Equal("state", invalid.FlagName)
+	suite.Assert().Equal([]string{"four"}, invalid.Values)
+}
+
+func (suite *FlagSuite) TestEnumSliceFlagFromEnvironment() {
+	suite.T().Setenv("TEST_STATE", "all,-two")
+	root := suite.NewCommandWithSlice()
+	state := flags.NewEnumSliceFlagWithAllAllowed("+one", "two", "three")
+	root.Flags().Var(state, "state", "State of the flag")
+	flags.EnableEnvironment(root, "TEST")
+
+	output, err := suite.Execute(root)
+	suite.Require().NoError(err)
+	suite.Assert().Equal("[one three]", output)
+	suite.Assert().True(state.FromEnvironment())
+
+	suite.T().Setenv("TEST_STATE", "one,four")
+	root = suite.NewCommandWithSlice()
+	state = flags.NewEnumSliceFlag("+one", "two", "three")
+	root.Flags().Var(state, "state", "State of the flag")
+	flags.EnableEnvironment(root, "TEST")
+
+	_, err = suite.Execute(root)
+	suite.Require().Error(err, "four is not allowed")
+	suite.Assert().ErrorIs(err, errors.EnvironmentInvalid)
+	suite.Assert().ErrorIs(err, errors.ArgumentInvalid)
+	suite.Assert().Contains(err.Error(), "Environment variable TEST_STATE is invalid")
+}
+
+func (suite *FlagSuite) TestEnvironmentVariableName() {
+	suite.Assert().Equal("APP_LOG_LEVEL", flags.EnvironmentVariableName("app", "log-level"))
+	suite.Assert().Equal("STATE", flags.EnvironmentVariableName("", "state"))
+}
+
+func (suite *FlagSuite) TestFlagSources() {
+	suite.T().Setenv("TEST_LEVEL", "warn")
+	root := suite.NewCommand()
+	state := flags.NewEnumFlag("+one", "two", "three")
+	level := flags.NewEnumFlag("+info", "warn", "error")
+	outputs := flags.NewEnumSliceFlag("+json", "yaml", "table")
+	root.Flags().Var(state, "state", "State of the flag")
+	root.Flags().Var(level, "level", "Level")
+	root.Flags().Var(outputs, "outputs", "Outputs")
+	root.Flags().Bool("debug-flags", false, "Print the flags")
+	flags.EnableEnvironment(root, "TEST")
+
+	suite.Assert().Equal(flags.SourceDefault, state.Source())
+	_, err := suite.Execute(root, "--state", "two", "--debug-flags")
+	suite.Require().NoError(err)
+	suite.Assert().Equal(flags.SourceCommandLine, state.Source())
+	suite.Assert().Equal(flags.SourceEnvironment, level.Source())
+	suite.Assert().Equal(flags.SourceDefault, outputs.Source())
+
+	_ = outputs.Replace([]string{"yaml"})
+	suite.Assert().Equal(flags.SourceProgram, outputs.Source())
+	suite.Assert().Equal(flags.SourceCommandLine, flags.SourceOf(root.Flags().Lookup("debug-flags")))
+}
+
+func (suite *FlagSuite) TestCanPrintFlagSources() {
+	suite.T().Setenv("TEST_LEVEL", "warn")
+	root := &cobra.Command{Use: "root", RunE: func(cmd *cobra.Command, args []string) error {
+		flags.PrintSources(cmd)
+		return nil
+	}}
+	root.Flags().Var(flags.NewEnumFlag("+one", "two", "three"), "state", "State of the flag")
+	root.Flags().Var(flags.NewEnumFlag("+info", "warn", "error"), "level", "Level")
+	root.Flags().Var(flags.NewEnumSliceFlag("+json", "yaml", "table"), "outputs", "Outputs")
+	flags.EnableValidation(flags.EnableEnvironment(root, "TEST"))
+
+	output, err := suite.Execute(root, "--outputs", "yaml,table")
+	suite.Require().NoError(err)
+	suite.Assert().Equal("--help=false (default)\n--level=warn (environment)\n--outputs=[yaml,table] (command line)\n--state=one (default)\n", output)
+}
+
+func (suite *FlagSuite) NewCommandWithConfig(filename string) (*cobra.Command, *flags.EnumFlag, *flags.EnumSliceFlag) {
+	root := &cobra.Command{Use: "root", PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		return flags.LoadConfig(cmd, filename)
+	}, RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.Print(cmd.Flag("state").Value.String(), " ", cmd.Flag("outputs").Value.String())
+		return nil
+	}}
+	state := flags.NewEnumFlag("+one", "two", "three")
+	outputs := flags.NewEnumSliceFlag("+json", "yaml", "table")
+	root.PersistentFlags().Var(state, "state", "State of the flag")
+	root.PersistentFlags().Var(outputs, "outputs", "Outputs")
+	issue := &cobra.Command{Use: "issue"}
+	list := &cobra.Command{Use: "list", RunE: root.RunE}
+	issue.AddCommand(list)
+	root.AddCommand(issue)
+	return flags.EnableValidation(root), state, outputs
+}
+
+func (suite *FlagSuite) TestCanLoadConfig() {
+	root, state, outputs := suite.NewCommandWithConfig("testdata/config.yaml")
+	output, err := suite.Execute(root)
+	suite.Require().NoError(err)
+	suite.Assert().Equal("two [yaml,table]", output)
+	suite.Assert().Equal(flags.SourceConfig, state.Source())
+	suite.Assert().Equal(flags.SourceConfig, outputs.Source())
+
+	root, _, _ = suite.NewCommandWithConfig("testdata/config.json")
+	output, err = suite.Execute(root)
+	suite.Require().NoError(err)
+	suite.Assert().Equal("three [json,table]", output)
+}
+
+func (suite *FlagSuite) TestCanLoadConfigForSubcommand() {
+	root, _, _ := suite.NewCommandWithConfig("testdata/config.yaml")
+	output, err := suite.Execute(root, "issue", "list")
+	suite.Require().NoError(err)
+	suite.Assert().Equal("three [json]", output)
+}
+
+func (suite *FlagSuite) TestLoadConfigShouldNotOverrideCommandLine() {
+	root, state, _ := suite.NewCommandWithConfig("testdata/config.yaml")
+	output, err := suite.Execute(root, "--state", "one")
+	suite.Require().NoError(err)
+	suite.Assert().Equal("one [yaml,table]", output)
+	suite.Assert().Equal(flags.SourceCommandLine, state.Source())
+}
+
+func (suite *FlagSuite) TestLoadConfigWithInvalidValue() {
+	root, _, _ := suite.NewCommandWithConfig("testdata/invalid-config.yaml")
+	_, err := suite.Execute(root, "issue", "list")
+	suite.Require().Error(err, "csv is not allowed")
+	suite.Assert().ErrorIs(err, errors.ArgumentInvalid)
+	var configError *flags.ConfigError
+	suite.Require().ErrorAs(err, &configError)
+	suite.Assert().Equal("issue.list.outputs", configError.Key)
+	suite.Assert().Equal(4, configError.Line)
+	suite.Assert().Equal("testdata/invalid-config.yaml:4: key issue.list.outputs: unknown value 'csv' for flag --outputs, did you mean 'json'?", err.Error())
+}
+
+func (suite *FlagSuite) TestLoadConfigWithMissingFile() {
+	root, _, _ := suite.NewCommandWithConfig("testdata/missing.yaml")
+	_, err := suite.Execute(root)
+	suite.Require().Error(err, "the file does not exist")
+	suite.Assert().ErrorIs(err, os.ErrNotExist)
+}
+
+func (suite *FlagSuite) NewCommandWithEnabledConfig(filename string, rules ...flags.Rule) (*cobra.Command, *flags.EnumFlag, *flags.EnumSliceFlag) {
+	root := &cobra.Command{Use: "root", RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.Print(cmd.Flag("state").Value.String(), " ", cmd.Flag("outputs").Value.String())
+		return nil
+	}}
+	state := flags.NewEnumFlag("+one", "two", "three")
+	outputs := flags.NewEnumSliceFlag("+json", "yaml", "table")
+	root.PersistentFlags().Var(state, "state", "State of the flag")
+	root.PersistentFlags().Var(outputs, "outputs", "Outputs")
+	issue := &cobra.Command{Use: "issue"}
+	list := &cobra.Command{Use: "list", RunE: root.RunE}
+	issue.AddCommand(list)
+	root.AddCommand(issue)
+	return flags.EnableValidation(flags.AddRules(flags.EnableConfig(root, filename), rules...)), state, outputs
+}
+
+func (suite *FlagSuite) TestCanEnableConfig() {
+	root, state, outputs := suite.NewCommandWithEnabledConfig("testdata/config.yaml")
+	output, err := suite.Execute(root)
+	suite.Require().NoError(err)
+	suite.Assert().Equal("two [yaml,table]", output)
+	suite.Assert().Equal(flags.SourceConfig, state.Source())
+	suite.Assert().Equal(flags.SourceConfig, outputs.Source())
+
+	root, _, _ = suite.NewCommandWithEnabledConfig("testdata/config.yaml")
+	output, err = suite.Execute(root, "issue", "list", "--state", "one")
+	suite.Require().NoError(err)
+	suite.Assert().Equal("one [json]", output)
+
+	root, _, _ = suite.NewCommandWithEnabledConfig("testdata/missing.yaml")
+	output, err = suite.Execute(root)
+	suite.Require().NoError(err, "a missing configuration file has no values")
+	suite.Assert().Equal("one [json]", output)
+}
+
+func (suite *FlagSuite) TestEnableConfigShouldCheckConstraintsWithConfigValues() {
+	root, _, outputs := suite.NewCommandWithEnabledConfig("testdata/config.yaml")
+	outputs.MinCount = 2
+	output, err := suite.Execute(root)
+	suite.Require().NoError(err, "the configuration file gives 2 outputs")
+	suite.Assert().Equal("two [yaml,table]", output)
+
+	root, _, outputs = suite.NewCommandWithEnabledConfig("testdata/config.yaml")
+	outputs.MaxCount = 1
+	_, err = suite.Execute(root)
+	suite.Require().Error(err, "the configuration file gives 2 outputs")
+	var countError *flags.InvalidValueCountError
+	suite.Assert().ErrorAs(err, &countError)
+}
+
+func (suite *FlagSuite) TestEnableConfigShouldCheckRulesWithConfigValues() {
+	root, _, _ := suite.NewCommandWithEnabledConfig("testdata/config.yaml", flags.Forbids("state", "two", "outputs"))
+	_, err := suite.Execute(root)
+	suite.Require().Error(err, "the configuration file gives both state=two and outputs")
+	var ruleError *flags.RuleError
+	suite.Assert().ErrorAs(err, &ruleError)
+}
+
+func (suite *FlagSuite) TestEnableConfigShouldKeepAllWithAllowedFunc() {
+	root := &cobra.Command{Use: "root", RunE: func(cmd *cobra.Command, args []string) error { return nil }}
+	outputs := flags.NewEnumSliceFlagWithAllAllowedAndFunc(func(context context.Context, cmd *cobra.Command, args []string, toComplete string) ([]string, error) {
+		return []string{"json", "yaml", "table"}, nil
+	})
+	root.Flags().Var(outputs, "outputs", "Outputs")
+	root = flags.EnableValidation(flags.EnableConfig(root, "testdata/config-all.yaml"))
+
+	_, err := suite.Execute(root)
+	suite.Require().NoError(err)
+	suite.Assert().Equal(flags.SourceConfig, outputs.Source())
+	suite.Assert().Equal([]string{"all", "json", "yaml", "table"}, outputs.GetSlice())
+	payload, err := json.Marshal(outputs)
+	suite.Require().NoError(err)
+	suite.Assert().Equal(`["all"]`, string(payload))
+}
+
+func (suite *FlagSuite) TestLoadConfigShouldCheckConstraints() {
+	root, _, outputs := suite.NewCommandWithConfig("testdata/config.yaml")
+	outputs.MaxCount = 1
+	_, err := suite.Execute(root)
+	suite.Require().Error(err, "the configuration file gives 2 outputs")
+	var countError *flags.InvalidValueCountError
+	suite.Assert().ErrorAs(err, &countError)
+}
+
+func (suite *FlagSuite) TestResolverWithValuesRemovedOnCommandLine() {
+	for _, merge := range []bool{false, true} {
+		resolver := flags.NewResolver(flags.FromCommandLine(), flags.FromDefaults(map[string]string{"state": "two"}))
+		resolver.MergeSlices = merge
+		root := suite.NewCommandWithSlice()
+		root.PersistentPreRunE = func(cmd *cobra.Command, args []string) error {
+			return resolver.Resolve(cmd)
+		}
+		state := flags.NewEnumSliceFlagWithAllAllowed("+one", "two", "three")
+		root.Flags().Var(state, "state", "State of the flag")
+
+		output, err := suite.Execute(root, "--state", "-one")
+		suite.Require().NoErrorf(err, "MergeSlices: %t", merge)
+		if merge {
+			suite.Assert().Equal("[two]", output)
+		} else {
+			suite.Assert().Equal("[]", output)
+		}
+		suite.Assert().Equal(flags.SourceCommandLine, state.Source())
+	}
+}
+
+func (suite *FlagSuite) NewCommandWithResolver(resolver *flags.Resolver) (*cobra.Command, *flags.EnumFlag, *flags.EnumSliceFlag) {
+	root := &cobra.Command{Use: "root", PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		return resolver.Resolve(cmd)
+	}, RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.Print(cmd.Flag("state").Value.String(), " ", cmd.Flag("outputs").Value.String())
+		return nil
+	}}
+	state := flags.NewEnumFlag("+one", "two", "three")
+	outputs := flags.NewEnumSliceFlag("+json", "yaml", "table")
+	root.Flags().Var(state, "state", "State of the flag")
+	root.Flags().Var(outputs, "outputs", "Outputs")
+	return root, state, outputs
+}
+
+func (suite *FlagSuite) TestResolverWithCommandLineFirst() {
+	suite.T().Setenv("TEST_STATE", "two")
+	suite.T().Setenv("TEST_OUTPUTS", "yaml")
+	resolver := flags.NewResolver(flags.FromCommandLine(), flags.FromEnvironment("TEST"), flags.FromConfig("testdata/resolver.yaml"))
+
+	root, state, outputs := suite.NewCommandWithResolver(resolver)
+	output, err := suite.Execute(root, "--outputs", "table")
+	suite.Require().NoError(err)
+	suite.Assert().Equal("two [table]", output)
+	suite.Assert().Equal(flags.SourceEnvironment, state.Source())
+	suite.Assert().Equal(flags.SourceCommandLine, outputs.Source())
+}
+
+func (suite *FlagSuite) TestResolverWithConfigBeforeEnvironment() {
+	suite.T().Setenv("TEST_STATE", "two")
+	resolver := flags.NewResolver(flags.FromCommandLine(), flags.FromConfig("testdata/resolver.yaml"), flags.FromEnvironment("TEST"))
+
+	root, state, _ := suite.NewCommandWithResolver(resolver)
+	output, err := suite.Execute(root)
+	suite.Require().NoError(err)
+	suite.Assert().Equal("three [table]", output)
+	suite.Assert().Equal(flags.SourceConfig, state.Source())
+}
+
+func (suite *FlagSuite) TestResolverCanMergeSlices() {
+	suite.T().Setenv("TEST_OUTPUTS", "yaml")
+	resolver := flags.NewResolver(flags.FromCommandLine(), flags.FromEnvironment("TEST"), flags.FromConfig("testdata/resolver.yaml"), flags.FromDefaults(map[string]string{"state": "two"}))
+	resolver.MergeSlices = true
+
+	root, state, outputs := suite.NewCommandWithResolver(resolver)
+	output, err := suite.Execute(root, "--outputs", "json")
+	suite.Require().NoError(err)
+	suite.Assert().Equal("three [json,yaml,table]", output)
+	suite.Assert().Equal(flags.SourceConfig, state.Source())
+	suite.Assert().Equal(flags.SourceCommandLine, outputs.Source())
+}
+
+func (suite *FlagSuite) TestResolverWithDefaults() {
+	resolver := flags.NewResolver(flags.FromCommandLine(), flags.FromConfig("testdata/missing.yaml"), flags.FromDefaults(map[string]string{"state": "two", "outputs": "yaml,table"}))
+
+	root, state, _ := suite.NewCommandWithResolver(resolver)
+	output, err := suite.Execute(root)
+	suite.Require().NoError(err)
+	suite.Assert().Equal("two [yaml,table]", output)
+	suite.Assert().Equal(flags.SourceProgram, state.Source())
+}
+
+func (suite *FlagSuite) TestResolverWithInvalidValues() {
+	suite.T().Setenv("TEST_STATE", "four")
+	resolver := flags.NewResolver(flags.FromCommandLine(), flags.FromEnvironment("TEST"))
+
+	root, _, _ := suite.NewCommandWithResolver(resolver)
+	_, err := suite.Execute(root)
+	suite.Require().Error(err, "four is not allowed")
+	suite.Assert().ErrorIs(err, errors.EnvironmentInvalid)
+	var invalid *flags.InvalidEnumValueError
+	suite.Require().ErrorAs(err, &invalid)
+	suite.Assert().Equal("state", invalid.FlagName)
+
+	resolver = flags.NewResolver(flags.FromCommandLine(), flags.FromConfig("testdata/invalid-resolver.yaml"))
+	root, _, _ = suite.NewCommandWithResolver(resolver)
+	_, err = suite.Execute(root)
+	suite.Require().Error(err, "csv is not allowed")
+	var configError *flags.ConfigError
+	suite.Require().ErrorAs(err, &configError)
+	suite.Assert().Equal("outputs", configError.Key)
+	suite.Assert().Equal(2, configError.Line)
+}
+
+type JobSpec struct {
+	State   *flags.EnumFlag      `json:"state" yaml:"state"`
+	Outputs *flags.EnumSliceFlag `json:"outputs" yaml:"outputs"`
+}
+
+func (suite *FlagSuite) NewJobSpec() JobSpec {
+	return JobSpec{
+		State:   flags.NewEnumFlag("+one", "two", "three"),
+		Outputs: flags.NewEnumSliceFlagWithAllAllowed("+json", "yaml", "table"),
+	}
+}
+
+func (suite *FlagSuite) TestCanMarshalFlagsToJSON() {
+	spec := suite.NewJobSpec()
+	suite.Require().NoError(spec.State.Set("two"))
+	suite.Require().NoError(spec.Outputs.Set("yaml,table"))
+
+	payload, err := json.Marshal(spec)
+	suite.Require().NoError(err)
+	suite.Assert().JSONEq(`{"state": "two", "outputs": ["yaml", "table"]}`, string(payload))
+
+	unmarshaled := suite.NewJobSpec()
+	err = json.Unmarshal(payload, &unmarshaled)
+	suite.Require().NoError(err)
+	suite.Assert().Equal("two", unmarshaled.State.Value)
+	suite.Assert().Equal([]string{"yaml", "table"}, unmarshaled.Outputs.GetSlice())
+	suite.Assert().Equal(flags.SourceProgram, unmarshaled.Outputs.Source())
+}
+
+func (suite *FlagSuite) TestCanMarshalFlagsToYAML() {
+	spec := suite.NewJobSpec()
+	suite.Require().NoError(spec.Outputs.Set("all"))
+
+	payload, err := yaml.Marshal(spec)
+	suite.Require().NoError(err)
+	suite.Assert().Equal("state: one\noutputs:\n    - all\n", string(payload))
+
+	spec.Outputs.MarshalAllExpanded = true
+	payload, err = yaml.Marshal(spec)
+	suite.Require().NoError(err)
+	suite.Assert().Equal("state: one\noutputs:\n    - json\n    - yaml\n    - table\n", string(payload))
+
+	unmarshaled := suite.NewJobSpec()
+	err = yaml.Unmarshal([]byte("state: three\noutputs: [all]\n"), &unmarshaled)
+	suite.Require().NoError(err)
+	suite.Assert().Equal("three", unmarshaled.State.Value)
+	suite.Assert().Equal([]string{"all", "json", "yaml", "table"}, unmarshaled.Outputs.GetSlice())
+}
+
+func (suite *FlagSuite) TestUnmarshalFlagsShouldValidateValues() {
+	spec := suite.NewJobSpec()
+	err := json.Unmarshal([]byte(`{"state": "four"}`), &spec)
+	suite.Require().Error(err, "four is not allowed")
+	suite.Assert().ErrorIs(err, errors.ArgumentInvalid)
+
+	err = yaml.Unmarshal([]byte("outputs: [json, csv]\n"), &spec)
+	suite.Require().Error(err, "csv is not allowed")
+	var invalid *flags.InvalidEnumValueError
+	suite.Require().ErrorAs(err, &invalid)
+	suite.Assert().Equal([]string{"csv"}, invalid.Values)
+
+	state := flags.NewEnumFlagWithFunc("one", func(context.Context, *cobra.Command, []string, string) ([]string, error) {
+		return []string{"one", "two"}, nil
+	})
+	err = state.UnmarshalText([]byte("three"))
+	suite.Require().Error(err, "three is not allowed")
+}
+
+func (suite *FlagSuite) TestCanMarshalFlagsToText() {
+	outputs := flags.NewEnumSliceFlag("+json", "yaml", "table")
+	payload, err := outputs.MarshalText()
+	suite.Require().NoError(err)
+	suite.Assert().Equal("json", string(payload))
+
+	suite.Require().NoError(outputs.UnmarshalText([]byte("yaml,table")))
+	suite.Assert().Equal([]string{"yaml", "table"}, outputs.GetSlice())
+
+	suite.Require().NoError(outputs.UnmarshalText([]byte{}))
+	suite.Assert().Empty(outputs.GetSlice())
+}
+
+func (suite *FlagSuite) TestEnumSliceFlagWithQuotedValues() {
+	root := suite.NewCommandWithSlice()
+	state := flags.NewEnumSliceFlag("+one", "two, three", `say "four"`)
+	root.Flags().Var(state, "state", "State of the flag")
+
+	_, err := suite.Execute(root, "--state", `"two, three","say ""four"""`)
+	suite.Require().NoError(err)
+	suite.Assert().Equal([]string{"two, three", `say "four"`}, state.GetSlice())
+	suite.Assert().Equal(`["two, three","say ""four"""]`, state.String())
+
+	_, err = suite.Execute(root, "--state", `"two, three`)
+	suite.Require().Error(err, "the quote is not closed")
+	suite.Assert().ErrorIs(err, errors.ArgumentInvalid)
+}
+
+func (suite *FlagSuite) TestEnumSliceFlagSetStringShouldRoundTrip() {
+	testCases := [][]string{
+		{"one"},
+		{"one", "two, three"},
+		{`say "four"`, "one"},
+		{"[x]", "one"},
+		{},
+	}
+	for _, separator := range []rune{0, ';'} {
+		for _, values := range testCases {
+			state := flags.NewEnumSliceFlag("+one", "two, three", `say "four"`, "[x]")
+			state.Separator = separator
+			suite.Require().NoError(state.Replace(values))
+			copy := flags.NewEnumSliceFlag("+one", "two, three", `say "four"`, "[x]")
+			copy.Separator = separator
+			suite.Require().NoErrorf(copy.Set(state.String()), "Failed to set %s", state.String())
+			suite.Assert().ElementsMatchf(state.GetSlice(), copy.GetSlice(), "Values of %s do not round trip", state.String())
+			suite.Assert().Equal(state.String(), copy.String())
+
+			copy = flags.NewEnumSliceFlag("+one", "two, three", `say "four"`, "[x]")
+			copy.Separator = separator
+			suite.Require().NoErrorf(copy.UnmarshalText([]byte(state.String())), "Failed to unmarshal %s", state.String())
+			suite.Assert().ElementsMatchf(state.GetSlice(), copy.GetSlice(), "Values of %s do not round trip", state.String())
+		}
+	}
+}
+
+func (suite *FlagSuite) TestEnumSliceFlagSetWithBrackets() {
+	root := suite.NewCommandWithSlice()
+	state := flags.NewEnumSliceFlag("+one", "[x]")
+	root.Flags().Var(state, "state", "State of the flag")
+
+	output, err := suite.Execute(root, "--state", `"[x]"`)
+	suite.Require().NoError(err, "a quoted value in brackets is kept as it is")
+	suite.Assert().Equal("[[x]]", output)
+
+	root = suite.NewCommandWithSlice()
+	state = flags.NewEnumSliceFlag("+one", "two")
+	root.Flags().Var(state, "state", "State of the flag")
+	output, err = suite.Execute(root, "--state", "[]")
+	suite.Require().NoError(err, "[] gives no values")
+	suite.Assert().Equal("[]", output)
+}
+
+func (suite *FlagSuite) TestEnumSliceFlagWithInvalidSeparator() {
+	for _, separator := range []rune{'"', '\n', '\r'} {
+		state := flags.NewEnumSliceFlag("+one", "two")
+		state.Separator = separator
+		err := state.Set("two")
+		suite.Require().Errorf(err, "Separator %q should not be accepted", separator)
+		suite.Assert().ErrorIs(err, errors.ArgumentInvalid)
+		_, err = state.MarshalText()
+		suite.Require().Errorf(err, "Separator %q should not be accepted", separator)
+		suite.Assert().ErrorIs(err, errors.ArgumentInvalid)
+		suite.Assert().Equal("[one]", state.String())
+	}
+}
+
+func (suite *FlagSuite) TestEnumSliceFlagWithInvalidQuotesShouldGiveTheCause() {
+	state := flags.NewEnumSliceFlag("+one", "two")
+	err := state.Set(`"two`)
+	suite.Require().Error(err, "the quote is not closed")
+	suite.Assert().ErrorIs(err, errors.ArgumentInvalid)
+	var parseError *csv.ParseError
+	suite.Assert().ErrorAs(err, &parseError)
+}
+
+func (suite *FlagSuite) TestEnumSliceFlagWithSeparator() {
+	root := suite.NewCommandWithSlice()
+	state := flags.NewEnumSliceFlag("+a,b", "c|d", "e")
+	state.Separator = ';'
+	root.Flags().Var(state, "state", "State of the flag")
+	suite.Assert().Equal(`["a,b"]`, state.String())
+
+	output, err := suite.Execute(root, "--state", "c|d;e")
+	suite.Require().NoError(err)
+	suite.Assert().Equal([]string{"c|d", "e"}, state.GetSlice())
+	suite.Assert().Equal("[c|d,e]", state.String())
+	suite.Assert().Equal("[c|d e]", output, "GetStringSlice should give the values")
+
+	root = suite.NewCommandWithSlice()
+	state = flags.NewEnumSliceFlag("+a,b", "c|d", "e")
+	state.Separator = ';'
+	root.Flags().Var(state, "state", "State of the flag")
+	_, err = suite.Execute(root)
+	suite.Require().NoError(err)
+	values, err := root.Flags().GetStringSlice("state")
+	suite.Require().NoError(err)
+	suite.Assert().Equal([]string{"a,b"}, values)
+
+	state = flags.NewEnumSliceFlag("a;b", "c", "d")
+	state.Separator = '|'
+	suite.Require().NoError(state.Set("a;b|c"))
+	suite.Assert().Equal([]string{"a;b", "c"}, state.GetSlice())
+	payload, err := state.MarshalText()
+	suite.Require().NoError(err)
+	suite.Assert().Equal("a;b|c", string(payload))
+}
+
+type CommandOptions struct {
+	State   string   `flag:"state" short:"s" enum:"+one,two,three" usage:"State of the flag"`
+	Format  Format   `flag:"format" enum:"json,yaml,table" usage:"Format"`
+	Regions []string `flag:"region" enum:"+us-east,us-west,eu-west" all:"true" usage:"Regions"`
+	Outputs []Format `flag:"outputs" enum:"json,yaml,table" env:"TEST_OUTPUTS"`
+	Verbose bool
+}
+
+func (suite *FlagSuite) NewCommandWithOptions(options *CommandOptions) *cobra.Command {
+	root := &cobra.Command{Use: "root", RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.Print(options.State, " ", options.Format, " ", options.Regions, " ", options.Outputs)
+		return nil
+	}}
+	err := flags.Register(root, options)
+	suite.Require().NoError(err)
+	return flags.EnableValidation(root)
+}
+
+func (suite *FlagSuite) TestCanRegisterFlagsFromStruct() {
+	options := CommandOptions{Format: FormatYAML}
+	root := suite.NewCommandWithOptions(&options)
+	suite.Require().NotNil(root.Flags().Lookup("state"))
+	suite.Assert().Equal("s", root.Flags().Lookup("state").Shorthand)
+	suite.Assert().Nil(root.Flags().Lookup("verbose"))
+
+	output, err := suite.Execute(root)
+	suite.Require().NoError(err)
+	suite.Assert().Equal("one yaml [us-east] []", output)
+
+	suite.T().Setenv("TEST_OUTPUTS", "json,table")
+	options = CommandOptions{}
+	root = suite.NewCommandWithOptions(&options)
+	output, err = suite.Execute(root, "-s", "two", "--format", "table", "--region", "all,-us-west")
+	suite.Require().NoError(err)
+	suite.Assert().Equal("two table [us-east eu-west] [json table]", output)
+	suite.Assert().Equal([]Format{FormatJSON, FormatTable}, options.Outputs)
+}
+
+func (suite *FlagSuite) TestRegisterFlagsFromStructShouldValidateValues() {
+	options := CommandOptions{}
+	root := suite.NewCommandWithOptions(&options)
+	_, err := suite.Execute(root, "--state", "four")
+	suite.Require().Error(err, "four is not allowed")
+	suite.Assert().ErrorIs(err, errors.ArgumentInvalid)
+}
+
+func (suite *FlagSuite) TestRegisterFlagsFromStructShouldCompleteValues() {
+	options := CommandOptions{}
+	root := suite.NewCommandWithOptions(&options)
+	output, err := suite.Execute(root, "__complete", "--region", "")
+	suite.Require().NoError(err)
+	suite.Assert().Equal("us-east\nus-west\neu-west\nall\n:0\nCompletion ended with directive: ShellCompDirectiveDefault\n", output)
+}
+
+func (suite *FlagSuite) TestRegisterFlagsFromStructShouldCallPreRun() {
+	options := CommandOptions{}
+	called := false
+	root := &cobra.Command{Use: "root", PreRun: func(cmd *cobra.Command, args []string) {
+		called = true
+		suite.Assert().Equal("three", options.State)
+	}, Run: func(cmd *cobra.Command, args []string) {}}
+	suite.Require().NoError(flags.Register(root, &options))
+
+	_, err := suite.Execute(root, "--state", "three")
+	suite.Require().NoError(err)
+	suite.Assert().True(called, "PreRun should have been called")
+}
+
+func (suite *FlagSuite) TestRegisterFlagsFromStructShouldBindFields() {
+	options := CommandOptions{}
+	called := false
+	root := &cobra.Command{Use: "root", Run: func(cmd *cobra.Command, args []string) {}}
+	suite.Require().NoError(flags.Register(root, &options))
+	suite.Assert().Equal("one", options.State, "the field should get the default value right away")
+	suite.Assert().Equal([]string{"us-east"}, options.Regions)
+	root.PreRunE = func(cmd *cobra.Command, args []string) error {
+		called = true
+		suite.Assert().Equal("three", options.State)
+		suite.Assert().Equal([]string{"us-west", "eu-west"}, options.Regions)
+		return nil
+	}
+
+	_, err := suite.Execute(root, "--state", "three", "--region", "us-west,eu-west")
+	suite.Require().NoError(err)
+	suite.Assert().True(called, "PreRunE should have been called")
+
+	resolver := flags.NewResolver(flags.FromConfig("testdata/resolver.yaml"))
+	options = CommandOptions{}
+	root = &cobra.Command{Use: "root", Run: func(cmd *cobra.Command, args []string) {}}
+	suite.Require().NoError(flags.Register(root, &options))
+	root.PersistentPreRunE = func(cmd *cobra.Command, args []string) error {
+		return resolver.Resolve(cmd)
+	}
+	_, err = suite.Execute(root)
+	suite.Require().NoError(err)
+	suite.Assert().Equal("three", options.State, "the field should get the value of the resolver")
+}
+
+func (suite *FlagSuite) TestRegisterFlagsFromInvalidStruct() {
+	root := &cobra.Command{Use: "root"}
+	err := flags.Register(root, CommandOptions{})
+	suite.Require().Error(err, "options must be a pointer")
+	suite.Assert().ErrorIs(err, errors.ArgumentInvalid)
+
+	var invalidType struct {
+		Count int `flag:"count" enum:"1,2,3"`
+	}
+	err = flags.Register(root, &invalidType)
+	suite.Require().Error(err, "int fields are not supported")
+	suite.Assert().ErrorIs(err, errors.InvalidType)
+
+	var missingEnum struct {
+		State string `flag:"state"`
+	}
+	err = flags.Register(root, &missingEnum)
+	suite.Require().Error(err, "the enum tag is required")
+	suite.Assert().ErrorIs(err, errors.Empty)
+
+	invalidDefault := struct {
+		State string `flag:"state" enum:"one,two"`
+	}{State: "bogus"}
+	err = flags.Register(root, &invalidDefault)
+	suite.Require().Error(err, "bogus is not allowed")
+	suite.Assert().ErrorIs(err, errors.ArgumentInvalid)
+	suite.Assert().Equal("unknown value 'bogus' for flag --state, allowed values: one, two", err.Error())
+
+	invalidDefaults := struct {
+		Regions []string `flag:"region" enum:"us-east,us-west" all:"true"`
+	}{Regions: []string{"us-east", "mars"}}
+	err = flags.Register(root, &invalidDefaults)
+	suite.Require().Error(err, "mars is not allowed")
+	var invalid *flags.InvalidEnumValueError
+	suite.Require().ErrorAs(err, &invalid)
+	suite.Assert().Equal([]string{"mars"}, invalid.Values)
+	suite.Assert().Equal("region", invalid.FlagName)
+}
+
+func (suite *FlagSuite) TestEnableValidationShouldRejectUnknownCommands() {
+	ran := false
+	root := &cobra.Command{Use: "root", RunE: func(cmd *cobra.Command, args []string) error {
+		ran = true
+		return nil
+	}}
+	root.AddCommand(&cobra.Command{Use: "sub", Run: func(cmd *cobra.Command, args []string) {}})
+	root.Flags().Var(flags.NewEnumFlag("+one", "two"), "state", "State of the flag")
+	flags.EnableValidation(root)
+
+	_, err := suite.Execute(root, "typo")
+	suite.Require().Error(err, "typo is not a command")
+	suite.Assert().Equal(`unknown command "typo" for "root"`, err.Error())
+	suite.Assert().False(ran, "the root command should not run")
+
+	_, err = suite.Execute(root, "sup")
+	suite.Require().Error(err, "sup is not a command")
+	suite.Assert().Contains(err.Error(), "Did you mean this?\n\tsub\n")
+
+	_, err = suite.Execute(root, "sub", "arg")
+	suite.Require().NoError(err, "subcommands accept arguments")
+}
+
+func (suite *FlagSuite) TestAddRulesShouldRejectUnknownCommands() {
+	root := &cobra.Command{Use: "root", Run: func(cmd *cobra.Command, args []string) {}}
+	root.AddCommand(&cobra.Command{Use: "sub", Run: func(cmd *cobra.Command, args []string) {}})
+	flags.AddRules(root, flags.Requires("format", "table", "columns"))
+
+	_, err := suite.Execute(root, "typo")
+	suite.Require().Error(err, "typo is not a command")
+	suite.Assert().Equal(`unknown command "typo" for "root"`, err.Error())
+}
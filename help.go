@@ -0,0 +1,66 @@
+package flags
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// usageAnnotation is the flag annotation that keeps the usage of a flag before the allowed values are added to it
+const usageAnnotation = "github.com/gildas/go-flags/usage"
+
+// allowedLister describes a flag value that can list its allowed values for a command
+type allowedLister interface {
+	listAllowed(cmd *cobra.Command) ([]string, error)
+}
+
+// EnableAllowedInHelp shows the allowed values of the enum flags in the help and usage of the given command and its subcommands
+//
+// The allowed values are added to the usage of each flag, like:
+//
+//	--state strings   State of the flag (one|two|three) (default [one,two])
+//
+// If the allowed values come from a function, it is called with the command when the help or usage is shown.
+//
+// Subcommands that have their own help or usage function are not affected.
+func EnableAllowedInHelp(cmd *cobra.Command) *cobra.Command {
+	helpFunc := cmd.HelpFunc()
+	cmd.SetHelpFunc(func(cmd *cobra.Command, args []string) {
+		addAllowedToUsage(cmd)
+		helpFunc(cmd, args)
+	})
+	usageFunc := cmd.UsageFunc()
+	cmd.SetUsageFunc(func(cmd *cobra.Command) error {
+		addAllowedToUsage(cmd)
+		return usageFunc(cmd)
+	})
+	return cmd
+}
+
+// addAllowedToUsage adds the allowed values to the usage of the enum flags of the given command
+func addAllowedToUsage(cmd *cobra.Command) {
+	addAllowed := func(flag *pflag.Flag) {
+		lister, ok := flag.Value.(allowedLister)
+		if !ok {
+			return
+		}
+		allowed, err := lister.listAllowed(cmd)
+		if err != nil || len(allowed) == 0 {
+			return
+		}
+		if flag.Annotations == nil {
+			flag.Annotations = map[string][]string{}
+		}
+		if _, found := flag.Annotations[usageAnnotation]; !found {
+			flag.Annotations[usageAnnotation] = []string{flag.Usage}
+		}
+		if len(allowed) > maxListedValues {
+			allowed = append(allowed[:maxListedValues:maxListedValues], "...")
+		}
+		flag.Usage = fmt.Sprintf("%s (%s)", flag.Annotations[usageAnnotation][0], strings.Join(allowed, "|"))
+	}
+	cmd.Flags().VisitAll(addAllowed)
+	cmd.InheritedFlags().VisitAll(addAllowed)
+}
@@ -0,0 +1,294 @@
+package flags
+
+import (
+	"path"
+	"strings"
+
+	"github.com/gildas/go-core"
+)
+
+// matcher matches the values given on the command line against the allowed values of a flag
+//
+// The keywords (like "all") are matched like the allowed values, but they are not part of the allowed values.
+type matcher struct {
+	allowed         []string
+	aliases         map[string][]string
+	keywords        []string
+	caseInsensitive bool
+	abbreviations   bool
+	patterns        bool
+	ordered         bool
+}
+
+// match returns the allowed value or the keyword that matches the given value
+//
+// The returned value is spelled as in the allowed values, aliases and abbreviations are resolved to their allowed value.
+func (m matcher) match(value string) (string, bool) {
+	if allowed, found := m.lookup(value, false); found {
+		return allowed, true
+	}
+	if m.caseInsensitive {
+		if allowed, found := m.lookup(value, true); found {
+			return allowed, true
+		}
+	}
+	if m.abbreviations && len(value) > 0 {
+		if candidates := m.abbreviated(value); len(candidates) == 1 {
+			return candidates[0], true
+		}
+	}
+	return "", false
+}
+
+// invalid returns the error for the given values that do not match
+//
+// The error contains suggestions and, for ambiguous abbreviations, the allowed values they could stand for.
+func (m matcher) invalid(values ...string) error {
+	err := &InvalidEnumValueError{
+		Values:      values,
+		Allowed:     m.allowed,
+		Suggestions: map[string][]string{},
+		Candidates:  map[string][]string{},
+	}
+	for _, value := range values {
+		if m.abbreviations && len(value) > 0 {
+			if candidates := m.abbreviated(value); len(candidates) > 1 {
+				err.Candidates[value] = candidates
+				continue
+			}
+		}
+		if m.isPattern(value) {
+			err.NoMatch = append(err.NoMatch, value)
+			continue
+		}
+		if _, isRange := m.span(value); isRange {
+			err.NoMatch = append(err.NoMatch, value)
+			continue
+		}
+		if suggestions := m.suggestions(value); len(suggestions) > 0 {
+			err.Suggestions[value] = suggestions
+		}
+	}
+	return err
+}
+
+// lookup returns the allowed value or the keyword that is spelled like the given value or one of its aliases
+func (m matcher) lookup(value string, foldCase bool) (string, bool) {
+	equal := func(candidate string) bool {
+		return candidate == value || (foldCase && strings.EqualFold(candidate, value))
+	}
+	for _, allowed := range m.allowed {
+		if equal(allowed) {
+			return allowed, true
+		}
+	}
+	for _, allowed := range m.allowed {
+		for _, alias := range m.aliases[allowed] {
+			if equal(alias) {
+				return allowed, true
+			}
+		}
+	}
+	for _, keyword := range m.keywords {
+		if equal(keyword) {
+			return keyword, true
+		}
+	}
+	return "", false
+}
+
+// abbreviated returns the allowed values and the keywords that start with the given abbreviation
+//
+// Aliases that start with the abbreviation are resolved to their allowed value.
+func (m matcher) abbreviated(abbreviation string) []string {
+	candidates := []string{}
+	for _, allowed := range m.allowed {
+		if m.hasPrefix(allowed, abbreviation) && !core.Contains(candidates, allowed) {
+			candidates = append(candidates, allowed)
+		}
+		for _, alias := range m.aliases[allowed] {
+			if m.hasPrefix(alias, abbreviation) && !core.Contains(candidates, allowed) {
+				candidates = append(candidates, allowed)
+			}
+		}
+	}
+	for _, keyword := range m.keywords {
+		if m.hasPrefix(keyword, abbreviation) {
+			candidates = append(candidates, keyword)
+		}
+	}
+	return candidates
+}
+
+// suggestions returns the allowed values and the keywords that are the closest to the given value
+//
+// Only the values within maxSuggestionDistance edits of the given value are considered.
+func (m matcher) suggestions(value string) []string {
+	suggestions := []string{}
+	best := maxSuggestionDistance + 1
+	for _, candidate := range append(append([]string{}, m.allowed...), m.keywords...) {
+		distance := editDistance(strings.ToLower(value), strings.ToLower(candidate))
+		if distance < best {
+			best = distance
+			suggestions = []string{candidate}
+		} else if distance == best && !core.Contains(suggestions, candidate) {
+			suggestions = append(suggestions, candidate)
+		}
+	}
+	return suggestions
+}
+
+// isPattern tells if the given value is a shell pattern and patterns are accepted
+func (m matcher) isPattern(value string) bool {
+	return m.patterns && strings.ContainsAny(value, "*?[")
+}
+
+// expand returns the allowed values that match the given shell pattern, if it is a pattern
+//
+// See path.Match for the pattern syntax.
+func (m matcher) expand(pattern string) (matches []string, isPattern bool) {
+	if !m.isPattern(pattern) {
+		return nil, false
+	}
+	if m.caseInsensitive {
+		pattern = strings.ToLower(pattern)
+	}
+	matches = []string{}
+	for _, allowed := range m.allowed {
+		candidate := allowed
+		if m.caseInsensitive {
+			candidate = strings.ToLower(allowed)
+		}
+		if matched, err := path.Match(pattern, candidate); err == nil && matched {
+			matches = append(matches, allowed)
+		}
+	}
+	return matches, true
+}
+
+// span returns the allowed values in the given range or comparison, if it is one
+//
+// The allowed values are ordered as they are given. The supported expressions are:
+//
+//	from..to, >=value, >value, <=value, <value
+//
+// An expression whose bounds are not allowed values is not a range.
+func (m matcher) span(expression string) (values []string, isRange bool) {
+	if !m.ordered {
+		return nil, false
+	}
+	if from, to, found := strings.Cut(expression, ".."); found {
+		start, end := m.index(from), m.index(to)
+		if start < 0 || end < 0 {
+			return nil, false
+		}
+		if start > end {
+			start, end = end, start
+		}
+		return append([]string{}, m.allowed[start:end+1]...), true
+	}
+	for _, operator := range []string{">=", "<=", ">", "<"} {
+		if value, found := strings.CutPrefix(expression, operator); found {
+			index := m.index(value)
+			switch {
+			case index < 0:
+				return nil, false
+			case operator == ">=":
+				return append([]string{}, m.allowed[index:]...), true
+			case operator == ">":
+				return append([]string{}, m.allowed[index+1:]...), true
+			case operator == "<=":
+				return append([]string{}, m.allowed[:index+1]...), true
+			default:
+				return append([]string{}, m.allowed[:index]...), true
+			}
+		}
+	}
+	return nil, false
+}
+
+// index returns the position of the allowed value that matches the given value, -1 if there is none
+func (m matcher) index(value string) int {
+	if canonical, found := m.match(value); found {
+		for i, allowed := range m.allowed {
+			if allowed == canonical {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// intersect returns the given values that are also in the other values
+func intersect(values []string, others []string) []string {
+	result := make([]string, 0, len(values))
+	for _, value := range values {
+		if core.Contains(others, value) {
+			result = append(result, value)
+		}
+	}
+	return result
+}
+
+// completionPattern returns the pattern to use to complete a partially typed pattern
+func completionPattern(toComplete string) string {
+	if strings.HasSuffix(toComplete, "*") {
+		return toComplete
+	}
+	return toComplete + "*"
+}
+
+// literalPrefix returns the text of the given pattern before its first wildcard
+func literalPrefix(pattern string) string {
+	if index := strings.IndexAny(pattern, "*?[\\"); index >= 0 {
+		return pattern[:index]
+	}
+	return pattern
+}
+
+// hasPrefix tells if the given value starts with the given prefix
+func (m matcher) hasPrefix(value, prefix string) bool {
+	if m.caseInsensitive {
+		return strings.HasPrefix(strings.ToLower(value), strings.ToLower(prefix))
+	}
+	return strings.HasPrefix(value, prefix)
+}
+
+// filter returns the values that start with the given prefix
+func (m matcher) filter(values []string, prefix string) []string {
+	if len(prefix) == 0 {
+		return values
+	}
+	result := make([]string, 0, len(values))
+	for _, value := range values {
+		if m.hasPrefix(value, prefix) {
+			result = append(result, value)
+		}
+	}
+	return result
+}
+
+// maxSuggestionDistance is the maximum edit distance between a value and the allowed values it is suggested
+const maxSuggestionDistance = 2
+
+// editDistance returns the Levenshtein distance between the given strings
+func editDistance(source, target string) int {
+	s, t := []rune(source), []rune(target)
+	previous := make([]int, len(t)+1)
+	current := make([]int, len(t)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(s); i++ {
+		current[0] = i
+		for j := 1; j <= len(t); j++ {
+			cost := 1
+			if s[i-1] == t[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(t)]
+}
@@ -0,0 +1,208 @@
+package flags
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/gildas/go-core"
+	"github.com/gildas/go-errors"
+	"github.com/spf13/cobra"
+)
+
+// Register creates the EnumFlag and EnumSliceFlag flags described by the tags of the given struct fields and adds them to the command
+//
+// The options must be a pointer to a struct. Its fields with a "flag" tag become flags:
+//   - a string field (or a type based on string) becomes an EnumFlag,
+//   - a []string field (or a slice of a type based on string) becomes an EnumSliceFlag.
+//
+// The supported tags are:
+//   - flag: the name of the flag,
+//   - short: the shorthand of the flag,
+//   - enum: the comma separated allowed values, the default values are prepended with a +,
+//   - usage: the usage of the flag,
+//   - all: "true" if "all" is allowed (EnumSliceFlag only),
+//   - env: the environment variable of the flag (see EnumFlag.EnvVar).
+//
+// If the enum tag has no default value, the value of the field is the default value.
+// It must be an allowed value, otherwise the returned error is an InvalidEnumValueError.
+//
+// The env tag is only read when the flags are validated, so EnableValidation must be called on the command,
+// otherwise the environment variables are ignored.
+//
+// The completion function of each flag is registered, and each flag is bound to its struct field,
+// like pflag's StringVar: the field gets the default value right away, and the new value whenever the flag changes
+// (on the command line, from an environment variable, a configuration file, or a Resolver).
+//
+// Example:
+//
+//	var options struct {
+//		State   string   `flag:"state" enum:"+one,two,three" usage:"State of the flag"`
+//		Regions []string `flag:"region" enum:"us-east,us-west,eu-west" all:"true" usage:"Regions"`
+//	}
+//	err := flags.Register(cmd, &options)
+//	cmd = flags.EnableValidation(cmd)
+func Register(cmd *cobra.Command, options interface{}) error {
+	target := reflect.ValueOf(options)
+	if target.Kind() != reflect.Pointer || target.Elem().Kind() != reflect.Struct {
+		return errors.ArgumentInvalid.With("options", options)
+	}
+	target = target.Elem()
+	for i := 0; i < target.NumField(); i++ {
+		field := target.Type().Field(i)
+		name, found := field.Tag.Lookup("flag")
+		if !found || len(name) == 0 {
+			continue
+		}
+		if err := register(cmd, name, field, target.Field(i)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// register creates the flag for the given struct field, binds it to the field, and adds it to the command
+func register(cmd *cobra.Command, name string, field reflect.StructField, value reflect.Value) error {
+	if !value.CanSet() {
+		return errors.ArgumentInvalid.With("field", field.Name)
+	}
+	allowed := []string{}
+	if enum := field.Tag.Get("enum"); len(enum) > 0 {
+		allowed = strings.Split(enum, ",")
+	}
+	if len(allowed) == 0 {
+		return errors.Empty.With("enum tag of field " + field.Name)
+	}
+	switch {
+	case value.Kind() == reflect.String:
+		flag := NewEnumFlag(allowed...)
+		if len(flag.Value) == 0 && value.Len() > 0 {
+			if !core.Contains(flag.Allowed, value.String()) {
+				err := flag.matcher(flag.source().static()).invalid(value.String())
+				setFlagName(err, name)
+				return err
+			}
+			flag.Value = value.String()
+		}
+		flag.EnvVar = field.Tag.Get("env")
+		bound := &boundEnumFlag{EnumFlag: flag, write: func() { value.SetString(flag.Value) }}
+		bound.write()
+		cmd.Flags().VarP(bound, name, field.Tag.Get("short"), field.Tag.Get("usage"))
+		return cmd.RegisterFlagCompletionFunc(flag.CompletionFunc(name))
+	case value.Kind() == reflect.Slice && value.Type().Elem().Kind() == reflect.String:
+		flag := NewEnumSliceFlag(allowed...)
+		flag.AllAllowed = field.Tag.Get("all") == "true"
+		if len(flag.Default) == 0 {
+			invalid := []string{}
+			for i := 0; i < value.Len(); i++ {
+				if v := value.Index(i).String(); core.Contains(flag.Allowed, v) {
+					flag.Default = append(flag.Default, v)
+				} else {
+					invalid = append(invalid, v)
+				}
+			}
+			if len(invalid) > 0 {
+				err := flag.matcher(flag.source().static()).invalid(invalid...)
+				setFlagName(err, name)
+				return err
+			}
+		}
+		flag.EnvVar = field.Tag.Get("env")
+		bound := &boundEnumSliceFlag{EnumSliceFlag: flag, write: func() {
+			values := flag.current()
+			slice := reflect.MakeSlice(value.Type(), len(values), len(values))
+			for i, v := range values {
+				slice.Index(i).SetString(v)
+			}
+			value.Set(slice)
+		}}
+		bound.write()
+		cmd.Flags().VarP(bound, name, field.Tag.Get("short"), field.Tag.Get("usage"))
+		return cmd.RegisterFlagCompletionFunc(flag.CompletionFunc(name))
+	default:
+		return errors.InvalidType.With(field.Name, "string or []string")
+	}
+}
+
+// boundEnumFlag is an EnumFlag that writes its value into a struct field whenever it changes
+type boundEnumFlag struct {
+	*EnumFlag
+	write func()
+}
+
+// Set sets the flag value and writes it into the field
+//
+// implements pflag.Value
+func (flag *boundEnumFlag) Set(value string) error {
+	defer flag.write()
+	return flag.EnumFlag.Set(value)
+}
+
+// Validate validates the flag value and writes it into the field
+//
+// implements Validator
+func (flag *boundEnumFlag) Validate(cmd *cobra.Command, args []string) error {
+	defer flag.write()
+	return flag.EnumFlag.Validate(cmd, args)
+}
+
+// reset resets the flag to its default value and writes it into the field
+func (flag *boundEnumFlag) reset(defaultValue string) {
+	defer flag.write()
+	flag.EnumFlag.reset(defaultValue)
+}
+
+// setFrom sets the flag value from the given source and writes it into the field
+func (flag *boundEnumFlag) setFrom(value string, source Source) error {
+	defer flag.write()
+	return flag.EnumFlag.setFrom(value, source)
+}
+
+// boundEnumSliceFlag is an EnumSliceFlag that writes its values into a struct field whenever they change
+type boundEnumSliceFlag struct {
+	*EnumSliceFlag
+	write func()
+}
+
+// Set sets the flag values and writes them into the field
+//
+// implements pflag.Value
+func (flag *boundEnumSliceFlag) Set(value string) error {
+	defer flag.write()
+	return flag.EnumSliceFlag.Set(value)
+}
+
+// Append adds the given value to the flag values and writes them into the field
+//
+// implements pflag.SliceValue
+func (flag *boundEnumSliceFlag) Append(value string) error {
+	defer flag.write()
+	return flag.EnumSliceFlag.Append(value)
+}
+
+// Replace replaces the flag values and writes them into the field
+//
+// implements pflag.SliceValue
+func (flag *boundEnumSliceFlag) Replace(values []string) error {
+	defer flag.write()
+	return flag.EnumSliceFlag.Replace(values)
+}
+
+// Validate validates the flag values and writes them into the field
+//
+// implements Validator
+func (flag *boundEnumSliceFlag) Validate(cmd *cobra.Command, args []string) error {
+	defer flag.write()
+	return flag.EnumSliceFlag.Validate(cmd, args)
+}
+
+// reset resets the flag to its default values and writes them into the field
+func (flag *boundEnumSliceFlag) reset(defaultValue string) {
+	defer flag.write()
+	flag.EnumSliceFlag.reset(defaultValue)
+}
+
+// setFrom sets the flag values from the given source and writes them into the field
+func (flag *boundEnumSliceFlag) setFrom(value string, source Source) error {
+	defer flag.write()
+	return flag.EnumSliceFlag.setFrom(value, source)
+}
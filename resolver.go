@@ -0,0 +1,277 @@
+package flags
+
+import (
+	"os"
+	"strings"
+
+	"github.com/gildas/go-errors"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// ValueSource gives the values of flags to a Resolver
+type ValueSource interface {
+	// Source tells what kind of source this is
+	Source() Source
+
+	// Lookup returns the value of the given flag of the command in this source, if any
+	//
+	// The values of a slice flag are comma separated.
+	Lookup(cmd *cobra.Command, flag *pflag.Flag) (value string, found bool, err error)
+}
+
+// Resolver fills the EnumFlag and EnumSliceFlag flags of a command from an ordered list of sources
+//
+// The sources are ordered by precedence, the first source that has a value for a flag wins.
+// The flags that have no value in any source keep their default value.
+//
+// If MergeSlices is true, the values of an EnumSliceFlag are the union of the values of all the sources that have some.
+type Resolver struct {
+	Sources     []ValueSource
+	MergeSlices bool
+}
+
+// resolvable describes a flag value that can be filled by a Resolver
+type resolvable interface {
+	sourced
+	reset(defaultValue string)
+	setSource(source Source)
+}
+
+// resolvedValue is a value found in a source
+type resolvedValue struct {
+	value  string
+	source ValueSource
+}
+
+// NewResolver creates a new Resolver with the given sources, ordered by precedence
+//
+// Example:
+//
+//	resolver := flags.NewResolver(flags.FromCommandLine(), flags.FromConfig("/etc/myapp.yaml"), flags.FromEnvironment("MYAPP"))
+func NewResolver(sources ...ValueSource) *Resolver {
+	return &Resolver{Sources: sources}
+}
+
+// Resolve fills the EnumFlag and EnumSliceFlag flags of the given command from the sources of the Resolver
+//
+// The values are validated like on the command line, then the constraints of the flags are checked.
+//
+// The command line must be parsed, this is typically called in a PersistentPreRunE.
+//
+// Example:
+//
+//	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+//		return resolver.Resolve(cmd)
+//	}
+func (resolver Resolver) Resolve(cmd *cobra.Command) (err error) {
+	cmd.Flags().VisitAll(func(flag *pflag.Flag) {
+		if err != nil {
+			return
+		}
+		if target, ok := flag.Value.(resolvable); ok {
+			err = resolver.resolve(cmd, flag, target)
+		}
+	})
+	if err != nil {
+		return
+	}
+	return checkConstraints(cmd)
+}
+
+// resolve fills the given flag from the sources of the Resolver
+func (resolver Resolver) resolve(cmd *cobra.Command, flag *pflag.Flag, target resolvable) error {
+	_, isSlice := flag.Value.(pflag.SliceValue)
+	values := []resolvedValue{}
+	for _, source := range resolver.Sources {
+		value, found, err := source.Lookup(cmd, flag)
+		if err != nil {
+			return err
+		}
+		if found {
+			values = append(values, resolvedValue{value: value, source: source})
+			if !isSlice || !resolver.MergeSlices {
+				break
+			}
+		}
+	}
+	target.reset(flag.DefValue)
+	for _, value := range values {
+		err := target.setFrom(value.value, value.source.Source())
+		if validator, ok := flag.Value.(Validator); ok && err == nil {
+			err = validator.Validate(cmd, cmd.Flags().Args())
+		}
+		if err != nil {
+			setFlagName(err, flag.Name)
+			if wrapper, ok := value.source.(interface {
+				wrap(flag *pflag.Flag, err error) error
+			}); ok {
+				return wrapper.wrap(flag, err)
+			}
+			return err
+		}
+	}
+	if len(values) > 0 {
+		target.setSource(values[0].source.Source())
+	}
+	return nil
+}
+
+// commandLineSource gives the values given on the command line
+type commandLineSource struct{}
+
+// FromCommandLine returns a ValueSource that gives the values of the flags given on the command line
+func FromCommandLine() ValueSource {
+	return commandLineSource{}
+}
+
+// Source tells what kind of source this is
+//
+// implements ValueSource
+func (source commandLineSource) Source() Source {
+	return SourceCommandLine
+}
+
+// Lookup returns the value of the given flag if it was given on the command line
+//
+// implements ValueSource
+func (source commandLineSource) Lookup(cmd *cobra.Command, flag *pflag.Flag) (string, bool, error) {
+	if !flag.Changed {
+		return "", false, nil
+	}
+	if slice, ok := flag.Value.(pflag.SliceValue); ok {
+		return joinValues(flag.Value, slice.GetSlice()), true, nil
+	}
+	return flag.Value.String(), true, nil
+}
+
+// joinValues joins the given values of a slice flag, like the flag expects them in Set
+//
+// An empty slice is given as "[]", like String renders it, so it is not mistaken for an empty value.
+func joinValues(value pflag.Value, values []string) string {
+	if len(values) == 0 {
+		return "[]"
+	}
+	if joiner, ok := value.(interface{ join(values []string) string }); ok {
+		return joiner.join(values)
+	}
+	return strings.Join(values, ",")
+}
+
+// environmentSource gives the values of environment variables
+type environmentSource struct {
+	prefix string
+}
+
+// FromEnvironment returns a ValueSource that gives the values of the environment variables of the flags
+//
+// The environment variable of a flag is its EnvVar, or is derived from the prefix and the flag name (see EnvironmentVariableName).
+// Empty environment variables are ignored.
+func FromEnvironment(prefix string) ValueSource {
+	return environmentSource{prefix: prefix}
+}
+
+// Source tells what kind of source this is
+//
+// implements ValueSource
+func (source environmentSource) Source() Source {
+	return SourceEnvironment
+}
+
+// Lookup returns the value of the environment variable of the given flag, if it is set
+//
+// implements ValueSource
+func (source environmentSource) Lookup(cmd *cobra.Command, flag *pflag.Flag) (string, bool, error) {
+	value := os.Getenv(source.name(flag))
+	return value, len(value) > 0, nil
+}
+
+// name returns the name of the environment variable of the given flag
+func (source environmentSource) name(flag *pflag.Flag) string {
+	if value, ok := flag.Value.(environmental); ok && len(value.environmentVariable()) > 0 {
+		return value.environmentVariable()
+	}
+	return EnvironmentVariableName(source.prefix, flag.Name)
+}
+
+// wrap wraps the given error with the environment variable of the given flag
+func (source environmentSource) wrap(flag *pflag.Flag, err error) error {
+	name := source.name(flag)
+	return environmentError(name, os.Getenv(name), err)
+}
+
+// configSource gives the values of a configuration file
+type configSource struct {
+	filename string
+	path     string
+	values   map[string]configValue
+}
+
+// FromConfig returns a ValueSource that gives the values of a configuration file (see LoadConfig for its format)
+//
+// If the configuration file does not exist, it has no values.
+func FromConfig(filename string) ValueSource {
+	return &configSource{filename: filename}
+}
+
+// Source tells what kind of source this is
+//
+// implements ValueSource
+func (source configSource) Source() Source {
+	return SourceConfig
+}
+
+// Lookup returns the value of the given flag in the configuration file, if any
+//
+// implements ValueSource
+func (source *configSource) Lookup(cmd *cobra.Command, flag *pflag.Flag) (string, bool, error) {
+	if path := cmd.CommandPath(); source.values == nil || source.path != path {
+		payload, err := os.ReadFile(source.filename)
+		if os.IsNotExist(err) {
+			payload, err = []byte{}, nil
+		}
+		if err != nil {
+			return "", false, errors.WithStack(err)
+		}
+		values, err := parseConfig(payload, commandPath(cmd))
+		if err != nil {
+			return "", false, &ConfigError{Filename: source.filename, Cause: err}
+		}
+		source.values, source.path = values, path
+	}
+	value, found := source.values[flag.Name]
+	return value.text(flag.Value), found, nil
+}
+
+// wrap wraps the given error with the configuration file and the key of the given flag
+func (source configSource) wrap(flag *pflag.Flag, err error) error {
+	value := source.values[flag.Name]
+	return &ConfigError{Filename: source.filename, Key: value.key, Line: value.line, Cause: err}
+}
+
+// defaultsSource gives values set by the program
+type defaultsSource struct {
+	values map[string]string
+}
+
+// FromDefaults returns a ValueSource that gives the values set by the program, by flag name
+//
+// The values of a slice flag are comma separated.
+func FromDefaults(values map[string]string) ValueSource {
+	return defaultsSource{values: values}
+}
+
+// Source tells what kind of source this is
+//
+// implements ValueSource
+func (source defaultsSource) Source() Source {
+	return SourceProgram
+}
+
+// Lookup returns the value of the given flag set by the program, if any
+//
+// implements ValueSource
+func (source defaultsSource) Lookup(cmd *cobra.Command, flag *pflag.Flag) (string, bool, error) {
+	value, found := source.values[flag.Name]
+	return value, found, nil
+}
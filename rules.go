@@ -0,0 +1,127 @@
+package flags
+
+import (
+	"fmt"
+
+	"github.com/gildas/go-core"
+	"github.com/gildas/go-errors"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// Rule describes a constraint between the value of a flag and another flag
+//
+// When the flag has the value, the other flag is either required or forbidden.
+//
+// The value of the flag can come from the command line or from its default value.
+// For a slice flag (like an EnumSliceFlag), the rule applies when the value is one of its values.
+type Rule struct {
+	FlagName      string // The name of the flag whose value triggers the rule
+	Value         string // The value that triggers the rule
+	OtherFlagName string // The name of the other flag
+	Forbidden     bool   // If true, the other flag cannot be given, otherwise it must be given
+}
+
+// Requires creates a Rule where the other flag must be given when the flag has the value
+//
+// Example:
+//
+//	flags.AddRules(cmd, flags.Requires("format", "table", "columns"))
+func Requires(flagName, value, otherFlagName string) Rule {
+	return Rule{FlagName: flagName, Value: value, OtherFlagName: otherFlagName}
+}
+
+// Forbids creates a Rule where the other flag cannot be given when the flag has the value
+//
+// Example:
+//
+//	flags.AddRules(cmd, flags.Forbids("state", "closed", "assignee"))
+func Forbids(flagName, value, otherFlagName string) Rule {
+	return Rule{FlagName: flagName, Value: value, OtherFlagName: otherFlagName, Forbidden: true}
+}
+
+// AddRules adds rules to the given command
+//
+// The rules are checked after the command line is parsed and after the command's Args validator,
+// so the flags are already validated if EnableValidation was called on the command.
+//
+// The rules do not apply to the subcommands.
+//
+// Example:
+//
+//	cmd := flags.AddRules(
+//		&cobra.Command{Use: "myapp", RunE: run},
+//		flags.Requires("format", "table", "columns"),
+//		flags.Forbids("state", "closed", "assignee"),
+//	)
+func AddRules(cmd *cobra.Command, rules ...Rule) *cobra.Command {
+	argsValidator := cmd.Args
+	cmd.Args = func(cmd *cobra.Command, args []string) error {
+		if argsValidator != nil {
+			if err := argsValidator(cmd, args); err != nil {
+				return err
+			}
+		} else if err := legacyArgs(cmd, args); err != nil {
+			return err
+		}
+		for _, rule := range rules {
+			if err := rule.Check(cmd); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return cmd
+}
+
+// Check checks the rule against the flags of the given command
+//
+// The other flag is given if its value does not come from its default value (see SourceOf).
+//
+// If a flag of the rule does not exist in the command, it is considered as not having the value or as not given.
+func (rule Rule) Check(cmd *cobra.Command) error {
+	flag := cmd.Flags().Lookup(rule.FlagName)
+	if flag == nil || !hasValue(flag, rule.Value) {
+		return nil
+	}
+	other := cmd.Flags().Lookup(rule.OtherFlagName)
+	if given := other != nil && SourceOf(other) != SourceDefault; given == rule.Forbidden {
+		return &RuleError{Rule: rule}
+	}
+	return nil
+}
+
+// hasValue tells if the given flag has the given value
+func hasValue(flag *pflag.Flag, value string) bool {
+	if slice, ok := flag.Value.(pflag.SliceValue); ok {
+		return core.Contains(slice.GetSlice(), value)
+	}
+	return flag.Value.String() == value
+}
+
+// RuleError is returned when a Rule is not followed
+//
+// The error is an errors.ArgumentMissing error if the other flag is required, an errors.ArgumentInvalid error if it is forbidden.
+type RuleError struct {
+	Rule Rule // The rule that is not followed
+}
+
+// Error returns the error message
+//
+// implements error
+func (err RuleError) Error() string {
+	if err.Rule.Forbidden {
+		return fmt.Sprintf("flag --%s cannot be used when --%s is %s", err.Rule.OtherFlagName, err.Rule.FlagName, err.Rule.Value)
+	}
+	return fmt.Sprintf("flag --%s is required when --%s is %s", err.Rule.OtherFlagName, err.Rule.FlagName, err.Rule.Value)
+}
+
+// Unwrap returns the errors.ArgumentMissing or errors.ArgumentInvalid error this error stands for
+//
+// implements errors.Unwrap
+func (err RuleError) Unwrap() error {
+	if err.Rule.Forbidden {
+		return errors.ArgumentInvalid.With(err.Rule.OtherFlagName)
+	}
+	return errors.ArgumentMissing.With(err.Rule.OtherFlagName)
+}
@@ -0,0 +1,80 @@
+package flags
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// Source tells where the value of a flag came from
+type Source int
+
+const (
+	// SourceDefault means the flag has its default value
+	SourceDefault Source = iota
+	// SourceEnvironment means the value came from an environment variable
+	SourceEnvironment
+	// SourceConfig means the value came from a configuration file
+	SourceConfig
+	// SourceCommandLine means the value came from the command line
+	SourceCommandLine
+	// SourceProgram means the value was set by the program, like with EnumSliceFlag.Replace
+	SourceProgram
+)
+
+// sourced describes a flag value that records where it came from
+type sourced interface {
+	Source() Source
+	setFrom(value string, source Source) error
+}
+
+// String returns the string representation of the source
+//
+// implements fmt.Stringer
+func (source Source) String() string {
+	switch source {
+	case SourceDefault:
+		return "default"
+	case SourceEnvironment:
+		return "environment"
+	case SourceConfig:
+		return "config"
+	case SourceCommandLine:
+		return "command line"
+	case SourceProgram:
+		return "program"
+	default:
+		return fmt.Sprintf("Source(%d)", int(source))
+	}
+}
+
+// SourceOf returns where the value of the given flag came from
+//
+// For the flags that do not record their source, the value came either from the command line or from the default value.
+func SourceOf(flag *pflag.Flag) Source {
+	if value, ok := flag.Value.(sourced); ok {
+		return value.Source()
+	}
+	if flag.Changed {
+		return SourceCommandLine
+	}
+	return SourceDefault
+}
+
+// PrintSources prints the value of all the flags of the given command and where they came from
+//
+// The flags are printed one per line in the command's output, like:
+//
+//	--state=two (environment)
+//
+// Example:
+//
+//	if debugFlags {
+//		flags.PrintSources(cmd)
+//	}
+func PrintSources(cmd *cobra.Command) {
+	cmd.Flags().VisitAll(func(flag *pflag.Flag) {
+		cmd.Printf("--%s=%s (%s)\n", flag.Name, flag.Value.String(), SourceOf(flag))
+	})
+}
@@ -0,0 +1,55 @@
+package flags
+
+// TypedEnumFlag represents an EnumFlag whose allowed values are Go constants of a string type
+//
+// Example:
+//
+//	type Format string
+//
+//	const (
+//		FormatJSON Format = "json"
+//		FormatYAML Format = "yaml"
+//	)
+//
+//	format := flags.NewTypedEnumFlag(FormatJSON, FormatJSON, FormatYAML)
+//	cmd.Flags().Var(format, "format", "Output format")
+//	. . .
+//	if format.Get() == FormatYAML { . . . }
+type TypedEnumFlag[T ~string] struct {
+	EnumFlag
+}
+
+// NewTypedEnumFlag creates a new TypedEnumFlag
+//
+// If the default value is empty, the flag will not have a default value.
+func NewTypedEnumFlag[T ~string](defaultValue T, allowed ...T) *TypedEnumFlag[T] {
+	return &TypedEnumFlag[T]{
+		EnumFlag: EnumFlag{
+			Allowed: toStrings(allowed),
+			Value:   string(defaultValue),
+		},
+	}
+}
+
+// Get returns the flag value as a T
+func (flag TypedEnumFlag[T]) Get() T {
+	return T(flag.Value)
+}
+
+// toStrings converts a slice of string typed values to a slice of strings
+func toStrings[T ~string](values []T) []string {
+	result := make([]string, 0, len(values))
+	for _, value := range values {
+		result = append(result, string(value))
+	}
+	return result
+}
+
+// fromStrings converts a slice of strings to a slice of string typed values
+func fromStrings[T ~string](values []string) []T {
+	result := make([]T, 0, len(values))
+	for _, value := range values {
+		result = append(result, T(value))
+	}
+	return result
+}
@@ -0,0 +1,49 @@
+package flags
+
+// TypedEnumSliceFlag represents an EnumSliceFlag whose allowed values are Go constants of a string type
+//
+// Example:
+//
+//	type Region string
+//
+//	const (
+//		RegionEU Region = "eu"
+//		RegionUS Region = "us"
+//	)
+//
+//	regions := flags.NewTypedEnumSliceFlag([]Region{RegionEU}, RegionEU, RegionUS)
+//	cmd.Flags().Var(regions, "region", "Regions")
+//	. . .
+//	for _, region := range regions.Values() { . . . }
+type TypedEnumSliceFlag[T ~string] struct {
+	EnumSliceFlag
+}
+
+// NewTypedEnumSliceFlag creates a new TypedEnumSliceFlag
+//
+// If no default value is provided, the flag will not have a default value.
+func NewTypedEnumSliceFlag[T ~string](defaultValues []T, allowed ...T) *TypedEnumSliceFlag[T] {
+	return &TypedEnumSliceFlag[T]{
+		EnumSliceFlag: EnumSliceFlag{
+			Allowed: toStrings(allowed),
+			Default: toStrings(defaultValues),
+		},
+	}
+}
+
+// NewTypedEnumSliceFlagWithAllAllowed creates a new TypedEnumSliceFlag
+//
+// If the flag is set to "all", all the allowed values are set.
+func NewTypedEnumSliceFlagWithAllAllowed[T ~string](defaultValues []T, allowed ...T) *TypedEnumSliceFlag[T] {
+	flag := NewTypedEnumSliceFlag(defaultValues, allowed...)
+	flag.AllAllowed = true
+	return flag
+}
+
+// Values returns the flag values as a slice of T
+//
+// If the flag was not set, the default values are returned.
+// Contrary to GetSlice, "all" is never part of the returned values.
+func (flag TypedEnumSliceFlag[T]) Values() []T {
+	return fromStrings[T](flag.current())
+}
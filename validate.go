@@ -0,0 +1,139 @@
+package flags
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gildas/go-errors"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// Validator describes a flag value that can only be validated once the command is known
+//
+// For example, the values of an EnumFlag that uses an AllowedFunc cannot be checked while the command line is parsed.
+type Validator interface {
+	Validate(cmd *cobra.Command, args []string) error
+}
+
+// constrained describes a flag value whose constraints are checked once the command line is parsed, even if the flag was not given
+type constrained interface {
+	checkConstraints(flagName string) error
+}
+
+// Validate validates the flags of the given command that were set on the command line
+//
+// First, the flags that were not given on the command line are set from their environment variable, if any (see EnableEnvironment),
+// then the flags that still have their default value are set from the configuration file, if any (see EnableConfig).
+//
+// Then, the flags whose value implements Validator are validated, unless they have their default value
+// or come from the configuration file, whose values are validated when it is read.
+//
+// Finally, the constraints of the flags are checked, like the MinCount and MaxCount of an EnumSliceFlag.
+//
+// If the returned error is an InvalidEnumValueError or a ConflictingValuesError, its FlagName is set.
+func Validate(cmd *cobra.Command, args []string) (err error) {
+	if err = loadEnvironment(cmd); err != nil {
+		return
+	}
+	if err = loadConfig(cmd); err != nil {
+		return
+	}
+	cmd.Flags().VisitAll(func(flag *pflag.Flag) {
+		if err != nil {
+			return
+		}
+		if !flag.Changed && SourceOf(flag) == SourceDefault {
+			return
+		}
+		if SourceOf(flag) == SourceConfig {
+			// the values of the configuration file were validated when the file was read
+			return
+		}
+		if validator, ok := flag.Value.(Validator); ok {
+			if err = validator.Validate(cmd, args); err != nil {
+				setFlagName(err, flag.Name)
+			}
+		}
+	})
+	if err != nil {
+		return
+	}
+	return checkConstraints(cmd)
+}
+
+// checkConstraints checks the constraints of the flags of the given command
+func checkConstraints(cmd *cobra.Command) (err error) {
+	cmd.Flags().VisitAll(func(flag *pflag.Flag) {
+		if err != nil {
+			return
+		}
+		if constrained, ok := flag.Value.(constrained); ok {
+			err = constrained.checkConstraints(flag.Name)
+		}
+	})
+	return
+}
+
+// setFlagName sets the FlagName of the given error, if it is an InvalidEnumValueError or a ConflictingValuesError
+func setFlagName(err error, flagName string) {
+	var invalid *InvalidEnumValueError
+	if errors.As(err, &invalid) {
+		invalid.FlagName = flagName
+	}
+	var conflicting *ConflictingValuesError
+	if errors.As(err, &conflicting) {
+		conflicting.FlagName = flagName
+	}
+}
+
+// EnableValidation enables the validation of the flags of the given command and its subcommands
+//
+// The validation runs after the command line is parsed and before the PersistentPreRun, PreRun, and Run functions.
+// It is chained with the command's Args validator, which is still called after the flags are validated.
+// If the command has no Args validator, cobra's default check is kept: a root command with subcommands rejects unknown commands.
+//
+// Subcommands added after this call are not affected.
+//
+// Example:
+//
+//	cmd := flags.EnableValidation(&cobra.Command{Use: "myapp", RunE: run})
+func EnableValidation(cmd *cobra.Command) *cobra.Command {
+	argsValidator := cmd.Args
+	cmd.Args = func(cmd *cobra.Command, args []string) error {
+		if err := Validate(cmd, args); err != nil {
+			return err
+		}
+		if argsValidator != nil {
+			return argsValidator(cmd, args)
+		}
+		return legacyArgs(cmd, args)
+	}
+	for _, child := range cmd.Commands() {
+		EnableValidation(child)
+	}
+	return cmd
+}
+
+// legacyArgs checks the arguments like cobra does when a command has no Args validator
+//
+// Cobra only runs this check when the Args validator is nil, so it must be kept when the Args validator is wrapped:
+// a root command with subcommands does not accept arguments, they are unknown commands.
+func legacyArgs(cmd *cobra.Command, args []string) error {
+	if !cmd.HasSubCommands() || cmd.HasParent() || len(args) == 0 {
+		return nil
+	}
+	var suggestions strings.Builder
+	if !cmd.DisableSuggestions {
+		if cmd.SuggestionsMinimumDistance <= 0 {
+			cmd.SuggestionsMinimumDistance = 2
+		}
+		if names := cmd.SuggestionsFor(args[0]); len(names) > 0 {
+			suggestions.WriteString("\n\nDid you mean this?\n")
+			for _, name := range names {
+				suggestions.WriteString(fmt.Sprintf("\t%v\n", name))
+			}
+		}
+	}
+	return errors.Errorf("unknown command %q for %q%s", args[0], cmd.CommandPath(), suggestions.String())
+}